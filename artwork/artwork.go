@@ -0,0 +1,156 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"player/paths"
+	"player/player"
+
+	"github.com/dhowden/tag"
+	_ "golang.org/x/image/webp"
+)
+
+var ErrNotFound = errors.New("no artwork found")
+
+// maxThumbnailWidth skips the full resolution thumbnails, a terminal panel
+// never needs more than this.
+const maxThumbnailWidth = 640
+
+var coverNames = []string{"cover", "folder", "front", "Cover", "Folder", "Front"}
+
+// Fetcher loads the artwork of a track: the cover embedded in a local file
+// or next to it, or the video thumbnail, downloaded once into Dir.
+type Fetcher struct {
+	Dir    string
+	Client *http.Client
+}
+
+func NewFetcher() Fetcher {
+	return Fetcher{
+		Dir:    filepath.Join(paths.CacheDir(), "art"),
+		Client: http.DefaultClient,
+	}
+}
+
+func (f Fetcher) Load(ctx context.Context, video player.VideoInfo) (image.Image, error) {
+	if video.IsLocal() {
+		return localCover(video.Path)
+	}
+
+	file := filepath.Join(f.Dir, cacheName(video.ID))
+	if data, err := os.ReadFile(file); err == nil {
+		return decode(data)
+	}
+
+	url := ThumbnailURL(video)
+	if url == "" {
+		return nil, ErrNotFound
+	}
+	data, err := f.download(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(f.Dir, 0o700); err == nil {
+		os.WriteFile(file, data, 0o644)
+	}
+	return img, nil
+}
+
+func (f Fetcher) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("thumbnail: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+}
+
+// ThumbnailURL picks the largest thumbnail under maxThumbnailWidth, and
+// falls back to the standard YouTube one when yt-dlp listed none.
+func ThumbnailURL(video player.VideoInfo) string {
+	best := -1
+	for i, t := range video.Thumbnails {
+		if t.Width > maxThumbnailWidth {
+			continue
+		}
+		if best < 0 || t.Width >= video.Thumbnails[best].Width {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return video.Thumbnails[best].URL
+	}
+	if n := len(video.Thumbnails); n > 0 {
+		return video.Thumbnails[n-1].URL
+	}
+	if video.ID == "" {
+		return ""
+	}
+	return "https://i.ytimg.com/vi/" + video.ID + "/hqdefault.jpg"
+}
+
+// localCover reads the picture embedded in the file tags, or a cover image
+// from the same directory.
+func localCover(file string) (image.Image, error) {
+	if f, err := os.Open(file); err == nil {
+		m, err := tag.ReadFrom(f)
+		f.Close()
+		if err == nil && m.Picture() != nil {
+			if img, err := decode(m.Picture().Data); err == nil {
+				return img, nil
+			}
+		}
+	}
+
+	dir := filepath.Dir(file)
+	for _, name := range coverNames {
+		for _, ext := range []string{".jpg", ".jpeg", ".png", ".webp"} {
+			data, err := os.ReadFile(filepath.Join(dir, name+ext))
+			if err != nil {
+				continue
+			}
+			return decode(data)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+func cacheName(id string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, id) + ".img"
+}
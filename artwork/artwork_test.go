@@ -0,0 +1,127 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"player/player"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+func TestThumbnailURL(t *testing.T) {
+	video := player.VideoInfo{ID: "dQw4w9WgXcQ", Thumbnails: []player.Thumbnail{
+		{URL: "small", Width: 168},
+		{URL: "medium", Width: 480},
+		{URL: "huge", Width: 1920},
+	}}
+	if got := ThumbnailURL(video); got != "medium" {
+		t.Errorf("ThumbnailURL = %q, want medium", got)
+	}
+	video.Thumbnails = nil
+	if got := ThumbnailURL(video); got != "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg" {
+		t.Errorf("fallback = %q", got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	// A 16:9 thumbnail in 1:2 cells is about three times wider than tall.
+	cols, rows := Fit(testImage(160, 90), 24, 10, 10, 20)
+	if cols != 24 || rows != 7 {
+		t.Errorf("Fit = %dx%d, want 24x7", cols, rows)
+	}
+	cols, rows = Fit(testImage(100, 100), 24, 10, 10, 20)
+	if cols != 20 || rows != 10 {
+		t.Errorf("Fit square = %dx%d, want 20x10", cols, rows)
+	}
+}
+
+func TestRenderSizes(t *testing.T) {
+	img := testImage(64, 64)
+	for name, s := range map[string]string{
+		"blocks": HalfBlocks(img, 12, 6),
+		"kitty":  KittyPlaceholder(1, 12, 6),
+	} {
+		if w, h := lipgloss.Width(s), lipgloss.Height(s); w != 12 || h != 6 {
+			t.Errorf("%s: %dx%d, want 12x6", name, w, h)
+		}
+	}
+}
+
+func TestKittyTransmitChunks(t *testing.T) {
+	// Noise does not compress, so the PNG spans several chunks.
+	img := testImage(200, 200)
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rnd.Uint32())
+	}
+	s := KittyTransmit(img, 7, 20, 10, 10, 20)
+	if !strings.HasPrefix(s, "\x1b_Ga=T,f=100,U=1,q=2,i=7,c=20,r=10,m=1;") {
+		t.Fatalf("unexpected header: %q", s[:40])
+	}
+	if !strings.HasSuffix(s, "\x1b\\") || !strings.Contains(s, "\x1b_Gm=0;") {
+		t.Error("last chunk is not terminated")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, tt := range []struct {
+		env  map[string]string
+		want Protocol
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, Kitty},
+		{map[string]string{"TERM": "xterm-kitty", "TMUX": "/tmp/tmux"}, Blocks},
+		{map[string]string{"TERM": "foot"}, Sixel},
+		{map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "WezTerm"}, Sixel},
+		{map[string]string{"TERM": "xterm-256color"}, Blocks},
+	} {
+		if got := Detect(func(k string) string { return tt.env[k] }); got != tt.want {
+			t.Errorf("Detect(%v) = %s, want %s", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCachesThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(32, 18))
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	f := Fetcher{Dir: t.TempDir(), Client: srv.Client()}
+	video := player.VideoInfo{ID: "abc", Thumbnails: []player.Thumbnail{{URL: srv.URL, Width: 32}}}
+	for range 2 {
+		img, err := f.Load(context.Background(), video)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != 32 {
+			t.Fatalf("width = %d", img.Bounds().Dx())
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("downloaded %d times, want 1", n)
+	}
+}
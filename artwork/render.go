@@ -0,0 +1,188 @@
+package artwork
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-sixel"
+	"golang.org/x/image/draw"
+	"golang.org/x/sys/unix"
+)
+
+// Protocol is the way the image reaches the terminal.
+type Protocol string
+
+const (
+	Blocks Protocol = "blocks"
+	Kitty  Protocol = "kitty"
+	Sixel  Protocol = "sixel"
+	None   Protocol = "none"
+)
+
+// ParseProtocol reads the art.protocol setting, "auto" or empty meaning
+// whatever the terminal supports.
+func ParseProtocol(s string) Protocol {
+	switch p := Protocol(strings.ToLower(s)); p {
+	case Blocks, Kitty, Sixel, None:
+		return p
+	}
+	return Detect(os.Getenv)
+}
+
+// Detect guesses the graphics support of the terminal from its environment.
+// Terminal multiplexers get half blocks, they do not pass images through.
+func Detect(getenv func(string) string) Protocol {
+	term := getenv("TERM")
+	switch {
+	case getenv("TMUX") != "" || strings.HasPrefix(term, "screen"):
+		return Blocks
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || getenv("TERM_PROGRAM") == "ghostty":
+		return Kitty
+	case strings.HasPrefix(term, "foot") || strings.Contains(term, "sixel") || term == "mlterm" ||
+		getenv("TERM_PROGRAM") == "WezTerm" || getenv("TERM_PROGRAM") == "iTerm.app":
+		return Sixel
+	}
+	return Blocks
+}
+
+// CellSize returns the size in pixels of a terminal cell, or a common 1:2
+// guess when the terminal does not report it.
+func CellSize() (width, height int) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 || ws.Xpixel == 0 || ws.Ypixel == 0 {
+		return 10, 20
+	}
+	return int(ws.Xpixel / ws.Col), int(ws.Ypixel / ws.Row)
+}
+
+// Fit returns the largest cell area within maxCols x maxRows that keeps the
+// aspect ratio of img.
+func Fit(img image.Image, maxCols, maxRows, cellW, cellH int) (cols, rows int) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 || maxCols <= 0 || maxRows <= 0 {
+		return 0, 0
+	}
+	cols = maxCols
+	rows = (cols*cellW*b.Dy()/b.Dx() + cellH/2) / cellH
+	if rows > maxRows {
+		rows = maxRows
+		cols = (rows*cellH*b.Dx()/b.Dy() + cellW/2) / cellW
+	}
+	return max(cols, 1), max(rows, 1)
+}
+
+func scale(img image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// HalfBlocks draws img with "▀" cells, the foreground colour being the top
+// pixel and the background the bottom one.
+func HalfBlocks(img image.Image, cols, rows int) string {
+	src := scale(img, cols, rows*2)
+	var b strings.Builder
+	for y := 0; y < rows; y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		for x := 0; x < cols; x++ {
+			b.WriteString(lipgloss.NewStyle().
+				Foreground(hex(src.At(x, 2*y))).
+				Background(hex(src.At(x, 2*y+1))).
+				Render("▀"))
+		}
+	}
+	return b.String()
+}
+
+func hex(c color.Color) lipgloss.Color {
+	r, g, b, _ := c.RGBA()
+	return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8))
+}
+
+// KittyTransmit uploads img under id with a virtual placement of cols x
+// rows cells, shown wherever KittyPlaceholder is printed.
+func KittyTransmit(img image.Image, id, cols, rows, cellW, cellH int) string {
+	var buf bytes.Buffer
+	png.Encode(&buf, scale(img, cols*cellW, rows*cellH))
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	const chunk = 4096
+	var b strings.Builder
+	for i := 0; i < len(data); i += chunk {
+		end := min(i+chunk, len(data))
+		more := 0
+		if end < len(data) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,U=1,q=2,i=%d,c=%d,r=%d,m=%d;%s\x1b\\", id, cols, rows, more, data[i:end])
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, data[i:end])
+		}
+	}
+	return b.String()
+}
+
+// KittyDelete frees the image uploaded under id.
+func KittyDelete(id int) string {
+	return fmt.Sprintf("\x1b_Ga=d,d=I,q=2,i=%d\x1b\\", id)
+}
+
+// KittyPlaceholder is the text standing for a virtual placement: the image
+// id is the foreground colour, the first cell of each row carries its row
+// and column diacritics and the terminal infers the following columns.
+func KittyPlaceholder(id, cols, rows int) string {
+	rows = min(rows, len(diacritics))
+	var b strings.Builder
+	for y := 0; y < rows; y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "\x1b[38;5;%dm", id)
+		for x := 0; x < cols; x++ {
+			b.WriteRune(placeholder)
+			if x == 0 {
+				b.WriteRune(diacritics[y])
+				b.WriteRune(diacritics[0])
+			}
+		}
+		b.WriteString("\x1b[39m")
+	}
+	return b.String()
+}
+
+// SixelAt draws img at the given zero-based cell, keeping the cursor where
+// it was so the Bubble Tea renderer does not lose track of it.
+func SixelAt(img image.Image, col, row, cols, rows, cellW, cellH int) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\x1b7\x1b[%d;%dH", row+1, col+1)
+	if err := sixel.NewEncoder(&buf).Encode(scale(img, cols*cellW, rows*cellH)); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\x1b8")
+	return buf.Bytes(), nil
+}
+
+const placeholder = '\U0010EEEE'
+
+// diacritics numbers the rows and columns of a placeholder, see
+// rowcolumn-diacritics.txt in the kitty sources.
+var diacritics = []rune{
+	0x0305, 0x030D, 0x030E, 0x0310, 0x0312, 0x033D, 0x033E, 0x033F,
+	0x0346, 0x034A, 0x034B, 0x034C, 0x0350, 0x0351, 0x0352, 0x0357,
+	0x035B, 0x0363, 0x0364, 0x0365, 0x0366, 0x0367, 0x0368, 0x0369,
+	0x036A, 0x036B, 0x036C, 0x036D, 0x036E, 0x036F, 0x0483, 0x0484,
+	0x0485, 0x0486, 0x0487, 0x0592, 0x0593, 0x0594, 0x0595, 0x0597,
+	0x0598, 0x0599, 0x059C, 0x059D, 0x059E, 0x059F, 0x05A0, 0x05A1,
+	0x05A8, 0x05A9, 0x05AB, 0x05AC, 0x05AF, 0x05C4, 0x0610, 0x0611,
+	0x0612, 0x0613, 0x0614, 0x0615, 0x0616, 0x0617, 0x0657, 0x0658,
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"player/config"
+	"player/daemon"
+	"player/doctor"
+	"player/equalizer"
+	"player/httpapi"
+	"player/logging"
+	"player/mpd"
+	"player/mpris"
+	"player/player"
+	"player/scrobble"
+
+	"github.com/charmbracelet/x/term"
+)
+
+type command struct {
+	help string
+	run  func(args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"daemon":       {"run the player daemon in the foreground", runDaemon},
+		"status":       {"show what is playing", withClient(printStatus)},
+		"play":         {"play a file, a URL or the first search result for a query", withClient(playQuery)},
+		"add":          {"queue a file, a URL or the first search result for a query", withClient(addQuery)},
+		"pause":        {"toggle pause", withClient(func(c *daemon.Client, _ []string) error { return c.TogglePause() })},
+		"stop":         {"stop playback", withClient(func(c *daemon.Client, _ []string) error { return c.Stop() })},
+		"next":         {"play the next queued track", withClient(func(c *daemon.Client, _ []string) error { return c.Next() })},
+		"prev":         {"play the previous queued track", withClient(func(c *daemon.Client, _ []string) error { return c.Previous() })},
+		"restart":      {"play the current track again from its start", withClient(func(c *daemon.Client, _ []string) error { return c.StartOver() })},
+		"queue":        {"list the queue", withClient(printQueue)},
+		"chapters":     {"list the chapters of the track, or go to the next, prev or nth one", withClient(setChapter)},
+		"eq":           {"show the equalizer, or apply a preset or ten gains in dB", withClient(setEqualizer)},
+		"norm":         {"show or set loudness normalization: off, track, album or dynamic", withClient(setNormalization)},
+		"crossfade":    {"show or set the crossfade between queued tracks in seconds", withClient(setCrossfade)},
+		"speed":        {"show or set the playback speed, from 0.5 to 3", withClient(setSpeed)},
+		"shuffle":      {"show or set the random order of the queue: on or off", withClient(setShuffle)},
+		"repeat":       {"show or set the repetition of the queue: on or off", withClient(setRepeat)},
+		"devices":      {"list the audio outputs, or switch to one and keep it", withClient(setDevice)},
+		"quit":         {"stop the daemon", quitDaemon},
+		"doctor":       {"check mpv, yt-dlp and ffmpeg, or install yt-dlp", runDoctor},
+		"lastfm-login": {"authorize scrobbling to a Last.fm account", lastFMLogin},
+	}
+}
+
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s [flags] [command]\n\nCommands:\n", fs.Name())
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "  %-13s %s\n", name, commands[name].help)
+		}
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+}
+
+func runDaemon(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	socket := fs.String("socket", daemon.DefaultSocketPath(), "control socket path")
+	withMPRIS := fs.Bool("mpris", true, "expose the player over MPRIS2 on the session bus")
+	mpdAddr := fs.String("mpd", "", "serve the MPD protocol on this address, e.g. localhost:6600")
+	withHTTP := fs.Bool("http", false, "serve the HTTP API and event stream")
+	httpAddr := fs.String("http-addr", httpapi.DefaultAddr, "HTTP API listen address")
+	httpToken := fs.String("http-token", os.Getenv("GHOST_PLAYER_TOKEN"), "token required by the HTTP API (default $GHOST_PLAYER_TOKEN)")
+	debug := fs.Bool("debug", os.Getenv(debugEnv) != "", "log debug records (default $"+debugEnv+")")
+	device := fs.String("device", "", "audio output for this run, see the devices command")
+	fs.Parse(args)
+
+	logFile, err := logging.Setup("daemon", *debug)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	for _, c := range doctor.Run(context.Background()) {
+		switch {
+		case !c.OK():
+			slog.Warn(c.Problem, "fix", c.Fix)
+		case c.Warning != "":
+			slog.Warn(c.Warning, "fix", c.Fix)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", config.Path(), err)
+	}
+	if *device != "" {
+		cfg.AudioDevice = *device
+	}
+
+	var frontends []daemon.Frontend
+	if services := scrobble.FromConfig(cfg.Scrobble); len(services) > 0 {
+		frontends = append(frontends, func(ctrl daemon.Controller) (io.Closer, error) {
+			s, err := scrobble.New(services, scrobble.DefaultQueuePath())
+			if err != nil {
+				return nil, err
+			}
+			return s.Attach(ctrl), nil
+		})
+	}
+	if *withMPRIS {
+		frontends = append(frontends, func(ctrl daemon.Controller) (io.Closer, error) {
+			return mpris.Start(ctrl)
+		})
+	}
+	if *mpdAddr != "" {
+		frontends = append(frontends, func(ctrl daemon.Controller) (io.Closer, error) {
+			return mpd.Listen(*mpdAddr, ctrl)
+		})
+	}
+	if *withHTTP {
+		frontends = append(frontends, func(ctrl daemon.Controller) (io.Closer, error) {
+			return httpapi.Listen(*httpAddr, *httpToken, ctrl)
+		})
+	}
+	return daemon.Run(*socket, cfg, frontends...)
+}
+
+// withClient wraps a subcommand that talks to a running daemon.
+func withClient(fn func(c *daemon.Client, args []string) error) func([]string) error {
+	return func(args []string) error {
+		fs := flag.NewFlagSet("client", flag.ExitOnError)
+		socket := fs.String("socket", daemon.DefaultSocketPath(), "daemon control socket")
+		fs.Parse(args)
+
+		c, err := daemon.Connect(*socket)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		return fn(c, fs.Args())
+	}
+}
+
+func lastFMLogin(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	lfm := cfg.Scrobble.LastFM
+	if lfm.APIKey == "" || lfm.Secret == "" {
+		return fmt.Errorf("set scrobble.lastfm.api_key and secret in %s first", config.Path())
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	fmt.Print("Last.fm username: ")
+	username, err := in.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	fmt.Print("Password: ")
+	password, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+
+	client := &scrobble.LastFM{APIKey: lfm.APIKey, Secret: lfm.Secret, BaseURL: lfm.BaseURL}
+	key, err := client.MobileSession(context.Background(), strings.TrimSpace(username), string(password))
+	if err != nil {
+		return err
+	}
+	cfg.Scrobble.LastFM.SessionKey = key
+	cfg.Scrobble.LastFM.Enabled = true
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Println("Scrobbling to Last.fm enabled, restart the daemon to apply.")
+	return nil
+}
+
+func quitDaemon(args []string) error {
+	fs := flag.NewFlagSet("quit", flag.ExitOnError)
+	socket := fs.String("socket", daemon.DefaultSocketPath(), "daemon control socket")
+	fs.Parse(args)
+
+	c, err := daemon.Dial(*socket)
+	if err != nil {
+		return fmt.Errorf("no daemon running: %w", err)
+	}
+	defer c.Close()
+	return c.Shutdown()
+}
+
+func printStatus(c *daemon.Client, _ []string) error {
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+	if status.Track == nil {
+		fmt.Println(status.State)
+		return nil
+	}
+	fmt.Printf("%s: %s - %s [%s/%s] %d%%\n",
+		status.State, status.Track.Uploader, status.Track.Title,
+		status.Info.Current, status.Info.Duration, status.Info.Progress)
+	if format := status.Info.Audio.String(); format != "" {
+		fmt.Println(format)
+	}
+	return nil
+}
+
+func printQueue(c *daemon.Client, _ []string) error {
+	videos, err := c.Queue()
+	if err != nil {
+		return err
+	}
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+	for i, video := range videos {
+		marker := " "
+		if i == status.Index {
+			marker = ">"
+		}
+		var failed string
+		if video.Failed != "" {
+			failed = " ✗ " + video.Failed.Label()
+		}
+		fmt.Printf("%s %2d. %s - %s%s\n", marker, i, video.Uploader, video.Title, failed)
+	}
+	return nil
+}
+
+func setEqualizer(c *daemon.Client, args []string) error {
+	if len(args) == 0 {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+		for i, f := range equalizer.Frequencies {
+			fmt.Printf("%6s %+5.1f dB\n", equalizer.Label(f), status.Equalizer[i])
+		}
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	presets := slices.Concat(equalizer.Presets, cfg.Equalizer.Presets)
+	if preset, ok := equalizer.Find(presets, strings.Join(args, " ")); ok {
+		return c.SetEqualizer(preset.Gains)
+	}
+
+	gains := make([]float64, len(args))
+	for i, arg := range args {
+		if gains[i], err = strconv.ParseFloat(arg, 64); err != nil {
+			names := make([]string, len(presets))
+			for i, p := range presets {
+				names[i] = p.Name
+			}
+			return fmt.Errorf("unknown preset %q, try one of: %s", strings.Join(args, " "), strings.Join(names, ", "))
+		}
+	}
+	return c.SetEqualizer(gains)
+}
+
+func setNormalization(c *daemon.Client, args []string) error {
+	if len(args) == 0 {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+		fmt.Println(status.Normalization)
+		return nil
+	}
+	return c.SetNormalization(args[0])
+}
+
+func setCrossfade(c *daemon.Client, args []string) error {
+	if len(args) == 0 {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%gs\n", status.Crossfade)
+		return nil
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSuffix(args[0], "s"), 64)
+	if err != nil || seconds < 0 || seconds > daemon.MaxCrossfade {
+		return fmt.Errorf("crossfade must be 0 to %d seconds", daemon.MaxCrossfade)
+	}
+	return c.SetCrossfade(seconds)
+}
+
+func setSpeed(c *daemon.Client, args []string) error {
+	if len(args) == 0 {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%g×\n", status.Speed)
+		return nil
+	}
+	speed, err := strconv.ParseFloat(strings.TrimRight(args[0], "x×"), 64)
+	if err != nil || speed < player.MinSpeed || speed > player.MaxSpeed {
+		return fmt.Errorf("speed must be %g to %g", player.MinSpeed, player.MaxSpeed)
+	}
+	return c.SetSpeed(speed)
+}
+
+func setShuffle(c *daemon.Client, args []string) error {
+	return setMode(c, args, "shuffle", func(s daemon.Status) bool { return s.Shuffle }, c.SetShuffle)
+}
+
+func setRepeat(c *daemon.Client, args []string) error {
+	return setMode(c, args, "repeat", func(s daemon.Status) bool { return s.Repeat }, c.SetRepeat)
+}
+
+// setMode shows the mode read by get, or turns it on or off with set.
+func setMode(c *daemon.Client, args []string, name string, get func(daemon.Status) bool, set func(bool) error) error {
+	if len(args) == 0 {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+		if get(status) {
+			fmt.Println("on")
+		} else {
+			fmt.Println("off")
+		}
+		return nil
+	}
+	switch args[0] {
+	case "on":
+		return set(true)
+	case "off":
+		return set(false)
+	}
+	return fmt.Errorf("%s must be on or off", name)
+}
+
+func setChapter(c *daemon.Client, args []string) error {
+	if len(args) > 0 && args[0] == "next" {
+		return c.NextChapter()
+	}
+	if len(args) > 0 && args[0] == "prev" {
+		return c.PreviousChapter()
+	}
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+	if len(status.Chapters) == 0 {
+		return errors.New("the track has no chapters")
+	}
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(status.Chapters) {
+			return fmt.Errorf("chapter must be next, prev or 1 to %d", len(status.Chapters))
+		}
+		return c.SetPosition(status.Chapters[n-1].Start)
+	}
+	current := player.ChapterAt(status.Chapters, status.Info.Position)
+	for i, chapter := range status.Chapters {
+		marker := " "
+		if i == current {
+			marker = ">"
+		}
+		start := time.Duration(chapter.Start) * time.Second
+		fmt.Printf("%s %2d. %8s %s\n", marker, i+1, start, chapter.Title)
+	}
+	return nil
+}
+
+// firstResult resolves the arguments to a track: a local file, a YouTube
+// URL or else the first search result.
+func firstResult(args []string) (player.VideoInfo, error) {
+	query := strings.Join(args, " ")
+	if query == "" {
+		return player.VideoInfo{}, errors.New("missing search query")
+	}
+	if video, err := player.LocalTrack(query); err == nil {
+		return video, nil
+	}
+	if video, ok := player.VideoFromURL(query); ok {
+		video.Title = video.URL
+		return video, nil
+	}
+	results, err := player.SearchYoutube(query, 1)
+	if err != nil {
+		return player.VideoInfo{}, err
+	}
+	return results[0], nil
+}
+
+func setDevice(c *daemon.Client, args []string) error {
+	if len(args) == 0 {
+		status, err := c.Status()
+		if err != nil {
+			return err
+		}
+		devices, err := c.AudioDevices()
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			mark := " "
+			if d.Name == status.Device {
+				mark = "*"
+			}
+			fmt.Printf("%s %-30s %s\n", mark, d.Name, d.Description)
+		}
+		return nil
+	}
+	if err := c.SetAudioDevice(args[0]); err != nil {
+		return err
+	}
+	return saveDevice(args[0])
+}
+
+// saveDevice keeps the audio output for the next start.
+func saveDevice(name string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cfg.AudioDevice = name
+	if name == player.AutoDevice {
+		cfg.AudioDevice = ""
+	}
+	return cfg.Save()
+}
+
+func playQuery(c *daemon.Client, args []string) error {
+	video, err := firstResult(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "playing %s\n", video.Title)
+	return c.Play(video)
+}
+
+func addQuery(c *daemon.Client, args []string) error {
+	video, err := firstResult(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "queued %s\n", video.Title)
+	return c.Enqueue(video)
+}
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	install := fs.Bool("install-ytdlp", false, "install or update the pinned yt-dlp "+player.YtdlpVersion+" in the data directory")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	if *install {
+		fmt.Printf("Installing yt-dlp %s...\n", player.YtdlpVersion)
+		installed, err := player.InstallYtdlp(ctx)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			fmt.Println("Already up to date.")
+		}
+	}
+	checks := doctor.Run(ctx)
+	doctor.Report(os.Stdout, checks)
+	if missing := doctor.Missing(checks); len(missing) > 0 {
+		return fmt.Errorf("%d required program(s) missing", len(missing))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"player/equalizer"
+	"player/paths"
+	"player/player"
+)
+
+// Config is the user configuration stored as JSON in the config directory.
+// Missing fields keep their zero value.
+type Config struct {
+	Scrobble  Scrobble  `json:"scrobble"`
+	Lyrics    Lyrics    `json:"lyrics"`
+	Art       Art       `json:"art"`
+	Equalizer Equalizer `json:"equalizer"`
+	// Normalization is the loudness mode the daemon starts with: off,
+	// track, album or dynamic.
+	Normalization string `json:"normalization,omitempty"`
+	// Crossfade is the overlap between queued tracks in seconds, up to 12.
+	Crossfade float64 `json:"crossfade,omitempty"`
+	// SearchTimeout is how long a search may take in seconds, 20 when
+	// unset.
+	SearchTimeout float64 `json:"search_timeout,omitempty"`
+	Ytdlp         Ytdlp   `json:"ytdlp"`
+	// Format chooses the stream of online tracks: preferred codec, bitrate
+	// cap and fallback yt-dlp format specs.
+	Format player.FormatPolicy `json:"format"`
+	// AudioDevice is the mpv audio output, auto or empty for the default.
+	// While it is missing the default is used.
+	AudioDevice string  `json:"audio_device,omitempty"`
+	Session     Session `json:"session"`
+}
+
+type Session struct {
+	// Resume loads the track of the last session paused where it was
+	// left; otherwise only the queue is restored.
+	Resume bool `json:"resume,omitempty"`
+	// LongTrack is the length in seconds from which a track plays again
+	// where it was left, 1200 when unset and negative to never resume.
+	LongTrack float64 `json:"long_track,omitempty"`
+}
+
+type Ytdlp struct {
+	// Managed keeps a pinned yt-dlp installed in the data directory and
+	// updates it when the app pins a newer release.
+	Managed bool `json:"managed,omitempty"`
+}
+
+// SearchTimeoutDuration is SearchTimeout as a duration, zero when unset.
+func (c Config) SearchTimeoutDuration() time.Duration {
+	return time.Duration(c.SearchTimeout * float64(time.Second))
+}
+
+type Equalizer struct {
+	// Gains are applied by the daemon on start, one per band in dB.
+	Gains   []float64          `json:"gains,omitempty"`
+	Presets []equalizer.Preset `json:"presets,omitempty"`
+}
+
+type Art struct {
+	// Protocol is auto, kitty, sixel, blocks or none.
+	Protocol string `json:"protocol,omitempty"`
+}
+
+type Lyrics struct {
+	// Dir holds .lrc files named after the video ID or title.
+	Dir string `json:"dir,omitempty"`
+	// SubLangs is passed to yt-dlp --sub-langs.
+	SubLangs string `json:"sub_langs,omitempty"`
+}
+
+type Scrobble struct {
+	LastFM       LastFM       `json:"lastfm"`
+	ListenBrainz ListenBrainz `json:"listenbrainz"`
+}
+
+type LastFM struct {
+	Enabled    bool   `json:"enabled"`
+	APIKey     string `json:"api_key"`
+	Secret     string `json:"secret"`
+	SessionKey string `json:"session_key"`
+	BaseURL    string `json:"base_url,omitempty"`
+}
+
+type ListenBrainz struct {
+	Enabled bool   `json:"enabled"`
+	Token   string `json:"token"`
+	BaseURL string `json:"base_url,omitempty"`
+}
+
+func Path() string {
+	return filepath.Join(paths.ConfigDir(), "config.json")
+}
+
+// Load reads the configuration file. A missing file yields the defaults.
+func Load() (Config, error) {
+	var c Config
+	data, err := os.ReadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// Save writes the configuration with owner-only permissions, since it may
+// hold credentials.
+func (c Config) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := Path() + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, Path())
+}
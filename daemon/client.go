@@ -0,0 +1,302 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"player/library"
+	"player/player"
+)
+
+var ErrClosed = errors.New("daemon connection closed")
+
+// Client talks to a running daemon. It is safe for concurrent use.
+type Client struct {
+	conn net.Conn
+
+	mu         sync.Mutex
+	enc        *json.Encoder
+	nextID     uint64
+	pending    map[string]chan rpcMessage
+	bus        *player.Bus
+	subscribed bool
+	closed     bool
+}
+
+// Dial connects to the daemon listening on path and checks that it speaks
+// the same API version.
+func Dial(path string) (*Client, error) {
+	nc, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:    nc,
+		enc:     json.NewEncoder(nc),
+		pending: make(map[string]chan rpcMessage),
+		bus:     player.NewBus(),
+	}
+	go c.read()
+
+	var hello helloResult
+	if err := c.call("daemon.hello", helloParams{Version: APIVersion}, &hello); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) read() {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.Method == "event" {
+			c.dispatch(msg.Params)
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[string(msg.ID)]
+		delete(c.pending, string(msg.ID))
+		c.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.bus.Close()
+}
+
+func (c *Client) dispatch(raw json.RawMessage) {
+	var e player.Event
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return
+	}
+	msg, err := player.DecodeEvent(e)
+	if err != nil {
+		return
+	}
+	c.bus.Publish(msg)
+}
+
+func (c *Client) call(method string, params, result any) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.nextID++
+	id := strconv.FormatUint(c.nextID, 10)
+	ch := make(chan rpcMessage, 1)
+	c.pending[id] = ch
+
+	var raw json.RawMessage
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			delete(c.pending, id)
+			c.mu.Unlock()
+			return err
+		}
+		raw = b
+	}
+	err := c.enc.Encode(rpcRequest{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: raw})
+	if err != nil {
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	msg, ok := <-ch
+	if !ok {
+		return ErrClosed
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if result != nil && len(msg.Result) > 0 {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("decoding %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) Play(video player.VideoInfo) error {
+	return c.call("player.play", video, nil)
+}
+
+func (c *Client) PlayIndex(index int) error {
+	return c.call("player.playIndex", indexParams{Index: index}, nil)
+}
+
+func (c *Client) Enqueue(videos ...player.VideoInfo) error {
+	return c.call("queue.add", videos, nil)
+}
+
+func (c *Client) Remove(index int) error {
+	return c.call("queue.remove", indexParams{Index: index}, nil)
+}
+
+func (c *Client) Queue() ([]player.VideoInfo, error) {
+	var videos []player.VideoInfo
+	err := c.call("queue.list", nil, &videos)
+	return videos, err
+}
+
+func (c *Client) Next() error {
+	return c.call("player.next", nil, nil)
+}
+
+func (c *Client) Previous() error {
+	return c.call("player.previous", nil, nil)
+}
+
+func (c *Client) StartOver() error {
+	return c.call("player.startOver", nil, nil)
+}
+
+func (c *Client) NextChapter() error {
+	return c.call("player.nextChapter", nil, nil)
+}
+
+func (c *Client) PreviousChapter() error {
+	return c.call("player.previousChapter", nil, nil)
+}
+
+func (c *Client) Stop() error {
+	return c.call("player.stop", nil, nil)
+}
+
+func (c *Client) TogglePause() error {
+	return c.call("player.togglePause", nil, nil)
+}
+
+func (c *Client) Seek(offset float64) error {
+	return c.call("player.seek", seekParams{Seconds: offset}, nil)
+}
+
+func (c *Client) SetPosition(pos float64) error {
+	return c.call("player.setPosition", seekParams{Seconds: pos}, nil)
+}
+
+func (c *Client) SetVolume(volume int) error {
+	return c.call("player.setVolume", volumeParams{Volume: volume}, nil)
+}
+
+func (c *Client) SetEqualizer(gains []float64) error {
+	return c.call("player.setEqualizer", equalizerParams{Gains: gains}, nil)
+}
+
+func (c *Client) SetNormalization(mode string) error {
+	return c.call("player.setNormalization", normalizationParams{Mode: mode}, nil)
+}
+
+func (c *Client) SetCrossfade(seconds float64) error {
+	return c.call("player.setCrossfade", seekParams{Seconds: seconds}, nil)
+}
+
+func (c *Client) SetSpeed(speed float64) error {
+	return c.call("player.setSpeed", speedParams{Speed: speed}, nil)
+}
+
+func (c *Client) SetShuffle(enabled bool) error {
+	return c.call("player.setShuffle", modeParams{Enabled: enabled}, nil)
+}
+
+func (c *Client) SetRepeat(enabled bool) error {
+	return c.call("player.setRepeat", modeParams{Enabled: enabled}, nil)
+}
+
+func (c *Client) SetVisualizer(enabled bool) error {
+	return c.call("player.setVisualizer", visualizerParams{Enabled: enabled}, nil)
+}
+
+func (c *Client) Spectrum() ([]float64, error) {
+	var levels []float64
+	err := c.call("player.spectrum", nil, &levels)
+	return levels, err
+}
+
+func (c *Client) AudioDevices() ([]player.AudioDevice, error) {
+	var devices []player.AudioDevice
+	err := c.call("player.devices", nil, &devices)
+	return devices, err
+}
+
+func (c *Client) SetAudioDevice(name string) error {
+	return c.call("player.setDevice", deviceParams{Name: name}, nil)
+}
+
+func (c *Client) Status() (Status, error) {
+	var status Status
+	err := c.call("player.status", nil, &status)
+	return status, err
+}
+
+func (c *Client) Library() ([]library.Record, error) {
+	var records []library.Record
+	err := c.call("library.list", nil, &records)
+	return records, err
+}
+
+// Shutdown asks the daemon to stop playback and exit.
+func (c *Client) Shutdown() error {
+	return c.call("daemon.shutdown", nil, nil)
+}
+
+// ForwardLogs adds the log records of this process to the events of the
+// daemon.
+func (c *Client) ForwardLogs() {
+	go forwardLogs(c.bus)
+}
+
+// Subscribe streams the daemon's player events. The channel is closed when
+// the connection to the daemon is lost.
+func (c *Client) Subscribe() (<-chan player.PlayerMsg, func()) {
+	events, unsubscribe := c.bus.Subscribe()
+	c.mu.Lock()
+	needSubscribe := !c.subscribed && !c.closed
+	c.subscribed = true
+	c.mu.Unlock()
+
+	if needSubscribe {
+		go c.subscribe()
+	}
+	return events, unsubscribe
+}
+
+// subscribe asks the daemon for its events. On failure the subscribers
+// are told and the next Subscribe tries again.
+func (c *Client) subscribe() {
+	err := c.call("events.subscribe", nil, nil)
+	if err == nil {
+		return
+	}
+	c.mu.Lock()
+	c.subscribed = false
+	c.mu.Unlock()
+	logger("client").Warn("subscribing to daemon events", "err", err)
+	c.bus.Publish(player.PlayErrorMsg{Err: fmt.Errorf("subscribing to daemon events: %w", err)})
+}
@@ -0,0 +1,127 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"player/config"
+	"player/library"
+	"player/logging"
+	"player/player"
+)
+
+// Frontend exposes the daemon through another protocol. It is started once
+// the service is up and closed on shutdown.
+type Frontend func(ctrl Controller) (io.Closer, error)
+
+// Run starts a daemon on socketPath and blocks until it is asked to shut
+// down or receives SIGINT/SIGTERM.
+func Run(socketPath string, cfg config.Config, frontends ...Frontend) error {
+	lib, err := library.Open(library.DefaultPath())
+	if err != nil {
+		return fmt.Errorf("opening library: %w", err)
+	}
+	if n, err := player.CleanOrphans(); err != nil {
+		logger("daemon").Warn("cleaning orphan mpv failed", "err", err)
+	} else if n > 0 {
+		logger("daemon").Info("stopped mpv left by a previous session", "count", n)
+	}
+	p := player.NewPlayer()
+	svc := NewService(p, lib)
+	svc.Configure(cfg)
+	svc.ForwardLogs()
+
+	srv, err := Listen(socketPath, svc)
+	if err != nil {
+		return err
+	}
+	go srv.Serve()
+
+	for _, start := range frontends {
+		closer, err := start(svc)
+		if err != nil {
+			logger("daemon").Error("frontend disabled", "err", err)
+			continue
+		}
+		defer closer.Close()
+		if remote, ok := closer.(interface{ RemoteURL() string }); ok {
+			svc.SetRemoteURL(remote.RemoteURL())
+		}
+	}
+
+	// Restoring may take as long as resolving a stream; a signal meanwhile
+	// must not kill the daemon before the session is saved.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	stopSession := RestoreSession(svc, cfg)
+	select {
+	case <-sig:
+	case <-srv.Done():
+	}
+
+	stopSession()
+	p.Close()
+	return srv.Close()
+}
+
+// RestoreSession puts back the session left by the previous run and keeps
+// it saved until the returned stop is called.
+func RestoreSession(svc *Service, cfg config.Config) (stop func()) {
+	sess, err := LoadSession(SessionPath())
+	if err == nil {
+		err = svc.Restore(sess, cfg.Session.Resume)
+	}
+	if err != nil {
+		logger("daemon").Error("restoring session failed", "err", err)
+	}
+	return svc.KeepSession(SessionPath(), SessionInterval)
+}
+
+// Connect dials the daemon, starting one in the background first if none
+// is listening on socketPath.
+func Connect(socketPath string) (*Client, error) {
+	if c, err := Dial(socketPath); err == nil {
+		return c, nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(exe, "daemon", "-socket", socketPath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting daemon: %w", err)
+	}
+	go cmd.Wait()
+
+	var lastErr error
+	for range 50 {
+		time.Sleep(100 * time.Millisecond)
+		c, err := Dial(socketPath)
+		if err == nil {
+			return c, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("daemon did not come up: %w", lastErr)
+}
+
+// forwardLogs publishes the log records of this process on bus.
+func forwardLogs(bus *player.Bus) {
+	records, _ := logging.Subscribe()
+	for msg := range records {
+		bus.Publish(msg)
+	}
+}
+
+// logger is the log of a part of the package: daemon or client.
+func logger(component string) *slog.Logger {
+	return slog.With("component", component)
+}
@@ -0,0 +1,402 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"player/library"
+	"player/loudness"
+	"player/player"
+)
+
+func startServer(t *testing.T) (*Service, string) {
+	t.Helper()
+	svc := NewService(player.NewPlayer(), nil)
+	path := filepath.Join(t.TempDir(), "daemon.sock")
+	srv, err := Listen(path, svc)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return svc, path
+}
+
+func TestClientQueueRoundTrip(t *testing.T) {
+	_, path := startServer(t)
+
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	videos := []player.VideoInfo{
+		{ID: "a", Title: "First"},
+		{ID: "b", Title: "Second"},
+	}
+	if err := c.Enqueue(videos...); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := c.Remove(0); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := c.Remove(5); err == nil {
+		t.Error("Remove(5) expected an error")
+	}
+
+	queue, err := c.Queue()
+	if err != nil {
+		t.Fatalf("Queue() error = %v", err)
+	}
+	if len(queue) != 1 || queue[0].ID != "b" {
+		t.Errorf("Queue() = %+v, want only %q", queue, "b")
+	}
+
+	status, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.State != "stopped" || status.Track != nil {
+		t.Errorf("Status() = %+v, want stopped with no track", status)
+	}
+}
+
+func TestClientsShareEvents(t *testing.T) {
+	_, path := startServer(t)
+
+	var events []<-chan player.PlayerMsg
+	for range 2 {
+		c, err := Dial(path)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		defer c.Close()
+		ch, _ := c.Subscribe()
+		events = append(events, ch)
+	}
+
+	sender, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer sender.Close()
+
+	// Subscriptions are registered asynchronously; retry until both see it.
+	deadline := time.After(2 * time.Second)
+	for i, ch := range events {
+		for received := false; !received; {
+			if err := sender.Enqueue(player.VideoInfo{ID: "x"}); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+			select {
+			case msg := <-ch:
+				_, received = msg.(player.QueueChangedMsg)
+			case <-time.After(50 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("client %d never received a queue event", i)
+			}
+		}
+	}
+}
+
+func TestClientEqualizer(t *testing.T) {
+	_, path := startServer(t)
+
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetEqualizer([]float64{3, -20}); err != nil {
+		t.Fatalf("SetEqualizer() error = %v", err)
+	}
+	status, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	want := []float64{3, -12, 0, 0, 0, 0, 0, 0, 0, 0}
+	if !slices.Equal(status.Equalizer, want) {
+		t.Errorf("Equalizer = %v, want %v", status.Equalizer, want)
+	}
+}
+
+func TestClientCrossfade(t *testing.T) {
+	_, path := startServer(t)
+
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	for _, tt := range []struct{ set, want float64 }{{4.5, 4.5}, {30, MaxCrossfade}, {-1, 0}} {
+		if err := c.SetCrossfade(tt.set); err != nil {
+			t.Fatalf("SetCrossfade(%g) error = %v", tt.set, err)
+		}
+		status, err := c.Status()
+		if err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+		if status.Crossfade != tt.want {
+			t.Errorf("SetCrossfade(%g): Crossfade = %g, want %g", tt.set, status.Crossfade, tt.want)
+		}
+	}
+}
+
+func TestNormalizationMeasuresStreamsOnce(t *testing.T) {
+	lib, err := library.Open(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(player.NewPlayer(), lib)
+	if err := svc.SetNormalization("loud"); err == nil {
+		t.Error("SetNormalization(loud) expected an error")
+	}
+	if err := svc.SetNormalization("track"); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := player.VideoInfo{ID: "a"}
+	if !svc.applyNormalization(stream) {
+		t.Error("an unmeasured stream should be measured")
+	}
+	lib.Update(stream, func(r *library.Record) {
+		r.Loudness = &loudness.Measurement{Integrated: -12, TruePeak: -1}
+	})
+	if svc.applyNormalization(stream) {
+		t.Error("a measured stream should not be measured again")
+	}
+	if svc.applyNormalization(player.VideoInfo{ID: "file:/a.flac", Path: "/a.flac"}) {
+		t.Error("local files use their ReplayGain tags")
+	}
+
+	status, _ := svc.Status()
+	if status.Normalization != "track" {
+		t.Errorf("Normalization = %q, want track", status.Normalization)
+	}
+}
+
+func TestSpeedIsRememberedPerUploader(t *testing.T) {
+	lib, err := library.Open(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(player.NewPlayer(), lib)
+	svc.Enqueue(player.VideoInfo{ID: "a", Uploader: "Talks"})
+	if _, err := svc.queue.Select(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.SetSpeed(1.5); err != nil {
+		t.Fatalf("SetSpeed() error = %v", err)
+	}
+	if got := svc.uploaderSpeed(player.VideoInfo{ID: "b", Uploader: "Talks"}); got != 1.5 {
+		t.Errorf("speed of the same uploader = %g, want 1.5", got)
+	}
+	if got := svc.uploaderSpeed(player.VideoInfo{ID: "c", Uploader: "Music"}); got != 1 {
+		t.Errorf("speed of another uploader = %g, want 1", got)
+	}
+
+	if err := svc.SetSpeed(5); err != nil {
+		t.Fatalf("SetSpeed() error = %v", err)
+	}
+	status, _ := svc.Status()
+	if status.Speed != player.MaxSpeed {
+		t.Errorf("Speed = %g, want %g", status.Speed, player.MaxSpeed)
+	}
+}
+
+func TestFailedTrackIsMarkedInQueue(t *testing.T) {
+	lib, err := library.Open(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(player.NewPlayer(), lib)
+	events, unsubscribe := svc.Subscribe()
+	defer unsubscribe()
+
+	track := player.VideoInfo{ID: "file:/missing.mp3", Path: "/missing.mp3"}
+	if err := svc.Play(track); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for marked := false; !marked; {
+		select {
+		case msg := <-events:
+			if _, ok := msg.(player.PlayStartedMsg); ok {
+				t.Fatal("PlayStartedMsg published for a track that failed")
+			}
+		case <-timeout:
+			t.Fatal("the failed track was not marked")
+		}
+		queue, _ := svc.Queue()
+		marked = queue[0].Failed != ""
+	}
+	if r, ok := lib.Get(track.ID); ok && r.PlayCount > 0 {
+		t.Errorf("record = %+v, want no play counted", r)
+	}
+}
+
+func TestLongTrackPositionIsKept(t *testing.T) {
+	lib, err := library.Open(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(player.NewPlayer(), lib)
+	podcast := player.VideoInfo{ID: "podcast"}
+	song := player.VideoInfo{ID: "song"}
+	lib.RecordPlay(podcast)
+	lib.RecordPlay(song)
+
+	svc.keepPosition(player.PlayerInfo{VideoID: "podcast", Position: 900, Length: 3600})
+	svc.keepPosition(player.PlayerInfo{VideoID: "song", Position: 60, Length: 180})
+	if left := svc.leftAt("podcast"); left != 900 {
+		t.Errorf("leftAt(podcast) = %g, want 900", left)
+	}
+	if left := svc.leftAt("song"); left != 0 {
+		t.Errorf("leftAt(song) = %g, want 0 for a short track", left)
+	}
+	if r, _ := lib.Get("podcast"); r.Heard() != 0.25 {
+		t.Errorf("Heard() = %g, want 0.25", r.Heard())
+	}
+
+	svc.keepPosition(player.PlayerInfo{VideoID: "podcast", Position: 3450, Length: 3600})
+	r, _ := lib.Get("podcast")
+	if !r.Completed || svc.leftAt("podcast") != 0 || r.Heard() != 1 {
+		t.Errorf("record = %+v, want completed and played from the start", r)
+	}
+}
+
+// refusingDaemon answers daemon.hello and fails every other call, counting
+// the events.subscribe requests.
+func refusingDaemon(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "daemon.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	var subscribes atomic.Int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				enc := json.NewEncoder(conn)
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					var req rpcRequest
+					if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+						return
+					}
+					resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+					switch req.Method {
+					case "daemon.hello":
+						resp.Result = helloResult{Version: APIVersion}
+					case "events.subscribe":
+						subscribes.Add(1)
+						fallthrough
+					default:
+						resp.Error = &rpcError{codeServerError, "refused"}
+					}
+					enc.Encode(resp)
+				}
+			}()
+		}
+	}()
+	return path, &subscribes
+}
+
+func TestClientReportsFailedSubscribe(t *testing.T) {
+	path, subscribes := refusingDaemon(t)
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+
+	events, unsubscribe := c.Subscribe()
+	defer unsubscribe()
+	select {
+	case msg := <-events:
+		if _, ok := msg.(player.PlayErrorMsg); !ok {
+			t.Fatalf("event = %#v, want a PlayErrorMsg", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the failed subscription was not reported")
+	}
+
+	_, unsubscribe2 := c.Subscribe()
+	defer unsubscribe2()
+	deadline := time.Now().Add(5 * time.Second)
+	for subscribes.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscribe did not try again after a failure")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestVisualizerIsCountedPerClient(t *testing.T) {
+	svc := NewService(player.NewPlayer(), nil)
+	_ = svc.SetVisualizer(true)
+	_ = svc.SetVisualizer(true)
+	_ = svc.SetVisualizer(false)
+	if svc.visualizers != 1 {
+		t.Fatalf("visualizers = %d after one of two clients left, want 1", svc.visualizers)
+	}
+	_ = svc.SetVisualizer(false)
+	_ = svc.SetVisualizer(false)
+	if svc.visualizers != 0 {
+		t.Errorf("visualizers = %d, want 0", svc.visualizers)
+	}
+	if levels, err := svc.Spectrum(); levels != nil || err != nil {
+		t.Errorf("Spectrum() = %v, %v with no client showing it", levels, err)
+	}
+}
+
+func TestMeasureStopsWithTheTrack(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	lib, err := library.Open(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(player.NewPlayer(), lib)
+
+	var ctx context.Context
+	svc.mu.Lock()
+	ctx, svc.stopMeasure = context.WithTimeout(context.Background(), measureTimeout)
+	svc.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		svc.measure(ctx, player.VideoInfo{ID: "song"}, "https://example.com/stream")
+		close(done)
+	}()
+	_ = svc.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the measure went on after Stop")
+	}
+	if r, _ := lib.Get("song"); r.Loudness != nil {
+		t.Errorf("loudness = %+v, want none saved", r.Loudness)
+	}
+}
@@ -0,0 +1,286 @@
+// Package daemontest provides a fake daemon.Controller for the tests of
+// the frontends.
+package daemontest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"player/daemon"
+	"player/library"
+	"player/player"
+)
+
+var _ daemon.Controller = (*Controller)(nil)
+
+// Controller keeps a real queue, state and volume and publishes the events
+// a Service would, without starting mpv. It records the name of every call;
+// the settings it does not model are accepted and ignored.
+type Controller struct {
+	queue *player.Queue
+	bus   *player.Bus
+
+	mu          sync.Mutex
+	state       string
+	volume      int
+	position    float64
+	speed       float64
+	calls       []string
+	subscribers int
+}
+
+func New() *Controller {
+	return &Controller{
+		queue:  player.NewQueue(),
+		bus:    player.NewBus(),
+		state:  player.StateName(player.Stopped),
+		volume: 100,
+		speed:  1,
+	}
+}
+
+// Publish sends msg to the subscribers.
+func (c *Controller) Publish(msg player.PlayerMsg) {
+	c.bus.Publish(msg)
+}
+
+// SetState changes the playback state and publishes the change.
+func (c *Controller) SetState(state string) {
+	c.mu.Lock()
+	c.state = state
+	c.mu.Unlock()
+	c.Publish(player.PlayerStateChangedMsg(state))
+}
+
+// Called reports whether the method name was called.
+func (c *Controller) Called(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Contains(c.calls, name)
+}
+
+// WaitSubscribers waits until n subscriptions were made, and reports
+// whether they were before timeout.
+func (c *Controller) WaitSubscribers(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		c.mu.Lock()
+		done := c.subscribers >= n
+		c.mu.Unlock()
+		if done {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (c *Controller) record(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, name)
+}
+
+func (c *Controller) Play(video player.VideoInfo) error {
+	c.record("Play")
+	index := c.queue.IndexOf(video.ID)
+	if index < 0 {
+		c.queue.Add(video)
+		index = c.queue.Len() - 1
+	}
+	return c.PlayIndex(index)
+}
+
+func (c *Controller) PlayIndex(index int) error {
+	c.record("PlayIndex")
+	video, err := c.queue.Select(index)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.position = 0
+	c.mu.Unlock()
+	c.Publish(c.queue.Changed())
+	c.Publish(player.PlayStartedMsg{VideoID: video.ID, Title: video.Title})
+	c.SetState(player.StateName(player.Playing))
+	return nil
+}
+
+func (c *Controller) Enqueue(videos ...player.VideoInfo) error {
+	c.record("Enqueue")
+	c.queue.Add(videos...)
+	c.Publish(c.queue.Changed())
+	return nil
+}
+
+func (c *Controller) Remove(index int) error {
+	c.record("Remove")
+	if err := c.queue.Remove(index); err != nil {
+		return err
+	}
+	c.Publish(c.queue.Changed())
+	return nil
+}
+
+func (c *Controller) Queue() ([]player.VideoInfo, error) {
+	return c.queue.Items(), nil
+}
+
+func (c *Controller) Next() error {
+	c.record("Next")
+	return c.PlayIndex(c.queue.Index() + 1)
+}
+
+func (c *Controller) Previous() error {
+	c.record("Previous")
+	return c.PlayIndex(c.queue.Index() - 1)
+}
+
+func (c *Controller) StartOver() error {
+	c.record("StartOver")
+	return c.PlayIndex(c.queue.Index())
+}
+
+func (c *Controller) NextChapter() error {
+	c.record("NextChapter")
+	return nil
+}
+
+func (c *Controller) PreviousChapter() error {
+	c.record("PreviousChapter")
+	return nil
+}
+
+func (c *Controller) Stop() error {
+	c.record("Stop")
+	c.SetState(player.StateName(player.Stopped))
+	c.Publish(player.PlayStoppedMsg{})
+	return nil
+}
+
+func (c *Controller) TogglePause() error {
+	c.record("TogglePause")
+	c.mu.Lock()
+	state := c.state
+	c.mu.Unlock()
+	switch state {
+	case player.StateName(player.Playing):
+		c.SetState(player.StateName(player.Paused))
+	case player.StateName(player.Paused):
+		c.SetState(player.StateName(player.Playing))
+	}
+	return nil
+}
+
+func (c *Controller) Seek(offset float64) error {
+	c.record("Seek")
+	c.mu.Lock()
+	c.position = max(c.position+offset, 0)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Controller) SetPosition(pos float64) error {
+	c.record("SetPosition")
+	c.mu.Lock()
+	c.position = max(pos, 0)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Controller) SetVolume(volume int) error {
+	c.record("SetVolume")
+	volume = max(0, min(volume, 100))
+	c.mu.Lock()
+	c.volume = volume
+	c.mu.Unlock()
+	c.Publish(player.VolumeChangedMsg(volume))
+	return nil
+}
+
+func (c *Controller) SetEqualizer([]float64) error {
+	c.record("SetEqualizer")
+	return nil
+}
+
+func (c *Controller) SetNormalization(string) error {
+	c.record("SetNormalization")
+	return nil
+}
+
+func (c *Controller) SetCrossfade(float64) error {
+	c.record("SetCrossfade")
+	return nil
+}
+
+func (c *Controller) SetSpeed(speed float64) error {
+	c.record("SetSpeed")
+	c.mu.Lock()
+	c.speed = speed
+	c.mu.Unlock()
+	c.Publish(player.SpeedChangedMsg(speed))
+	return nil
+}
+
+func (c *Controller) SetShuffle(enabled bool) error {
+	c.record("SetShuffle")
+	c.queue.SetShuffle(enabled)
+	return nil
+}
+
+func (c *Controller) SetRepeat(enabled bool) error {
+	c.record("SetRepeat")
+	c.queue.SetRepeat(enabled)
+	return nil
+}
+
+func (c *Controller) SetVisualizer(bool) error {
+	c.record("SetVisualizer")
+	return nil
+}
+
+func (c *Controller) Spectrum() ([]float64, error) {
+	return nil, nil
+}
+
+func (c *Controller) AudioDevices() ([]player.AudioDevice, error) {
+	return nil, nil
+}
+
+func (c *Controller) SetAudioDevice(string) error {
+	c.record("SetAudioDevice")
+	return nil
+}
+
+func (c *Controller) Status() (daemon.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := daemon.Status{
+		State:  c.state,
+		Info:   player.PlayerInfo{Position: c.position, Speed: c.speed},
+		Index:  c.queue.Index(),
+		Volume: c.volume,
+		Speed:  c.speed,
+		Device: player.AutoDevice,
+	}
+	status.Shuffle, status.Repeat = c.queue.Modes()
+	if video, ok := c.queue.Current(); ok {
+		status.Track = &video
+		status.Info.Length = video.Duration
+	}
+	return status, nil
+}
+
+func (c *Controller) Library() ([]library.Record, error) {
+	return nil, nil
+}
+
+func (c *Controller) Subscribe() (<-chan player.PlayerMsg, func()) {
+	c.mu.Lock()
+	c.subscribers++
+	c.mu.Unlock()
+	return c.bus.Subscribe()
+}
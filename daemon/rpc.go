@@ -0,0 +1,107 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"player/paths"
+)
+
+// APIVersion is bumped whenever a method changes in an incompatible way.
+const APIVersion = 1
+
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+	codeVersionError   = -32001
+)
+
+func DefaultSocketPath() string {
+	return filepath.Join(paths.RuntimeDir(), "daemon.sock")
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// rpcMessage is what a client reads: either a response or a notification.
+type rpcMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("daemon: %s (%d)", e.Message, e.Code)
+}
+
+type helloParams struct {
+	Version int `json:"version"`
+}
+
+type helloResult struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+type indexParams struct {
+	Index int `json:"index"`
+}
+
+type seekParams struct {
+	Seconds float64 `json:"seconds"`
+}
+
+type volumeParams struct {
+	Volume int `json:"volume"`
+}
+
+type speedParams struct {
+	Speed float64 `json:"speed"`
+}
+
+type modeParams struct {
+	Enabled bool `json:"enabled"`
+}
+
+type visualizerParams struct {
+	Enabled bool `json:"enabled"`
+}
+
+type deviceParams struct {
+	Name string `json:"name"`
+}
+
+type normalizationParams struct {
+	Mode string `json:"mode"`
+}
+
+type equalizerParams struct {
+	Gains []float64 `json:"gains"`
+}
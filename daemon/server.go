@@ -0,0 +1,308 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"player/player"
+)
+
+type handler func(params json.RawMessage) (any, error)
+
+// Server exposes a Controller as JSON-RPC 2.0 over a unix socket, one
+// message per line.
+type Server struct {
+	ctrl     Controller
+	listener net.Listener
+	path     string
+	handlers map[string]handler
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Listen binds the control socket. A stale socket left by a crashed daemon
+// is replaced, a live one is reported as an error.
+func Listen(path string, ctrl Controller) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("daemon already running on %s", path)
+	}
+	_ = os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	s := &Server{
+		ctrl:     ctrl,
+		listener: l,
+		path:     path,
+		done:     make(chan struct{}),
+	}
+	s.handlers = s.routes()
+	return s, nil
+}
+
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Done is closed once a client asked the daemon to shut down.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Server) Close() error {
+	s.stopOnce.Do(func() { close(s.done) })
+	err := s.listener.Close()
+	_ = os.Remove(s.path)
+	return err
+}
+
+func (s *Server) routes() map[string]handler {
+	noParams := func(fn func() error) handler {
+		return func(json.RawMessage) (any, error) { return nil, fn() }
+	}
+	withIndex := func(fn func(int) error) handler {
+		return func(raw json.RawMessage) (any, error) {
+			var p indexParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, fn(p.Index)
+		}
+	}
+	withSeconds := func(fn func(float64) error) handler {
+		return func(raw json.RawMessage) (any, error) {
+			var p seekParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, fn(p.Seconds)
+		}
+	}
+	return map[string]handler{
+		"player.play": func(raw json.RawMessage) (any, error) {
+			var video player.VideoInfo
+			if err := decodeParams(raw, &video); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.Play(video)
+		},
+		"player.playIndex":       withIndex(s.ctrl.PlayIndex),
+		"player.next":            noParams(s.ctrl.Next),
+		"player.previous":        noParams(s.ctrl.Previous),
+		"player.startOver":       noParams(s.ctrl.StartOver),
+		"player.nextChapter":     noParams(s.ctrl.NextChapter),
+		"player.previousChapter": noParams(s.ctrl.PreviousChapter),
+		"player.stop":            noParams(s.ctrl.Stop),
+		"player.togglePause":     noParams(s.ctrl.TogglePause),
+		"player.seek":            withSeconds(s.ctrl.Seek),
+		"player.setPosition":     withSeconds(s.ctrl.SetPosition),
+		"player.setVolume": func(raw json.RawMessage) (any, error) {
+			var p volumeParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetVolume(p.Volume)
+		},
+		"player.setEqualizer": func(raw json.RawMessage) (any, error) {
+			var p equalizerParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetEqualizer(p.Gains)
+		},
+		"player.setNormalization": func(raw json.RawMessage) (any, error) {
+			var p normalizationParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetNormalization(p.Mode)
+		},
+		"player.setCrossfade": withSeconds(s.ctrl.SetCrossfade),
+		"player.setSpeed": func(raw json.RawMessage) (any, error) {
+			var p speedParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetSpeed(p.Speed)
+		},
+		"player.setShuffle": func(raw json.RawMessage) (any, error) {
+			var p modeParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetShuffle(p.Enabled)
+		},
+		"player.setRepeat": func(raw json.RawMessage) (any, error) {
+			var p modeParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetRepeat(p.Enabled)
+		},
+		"player.setVisualizer": func(raw json.RawMessage) (any, error) {
+			var p visualizerParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetVisualizer(p.Enabled)
+		},
+		"player.spectrum": func(json.RawMessage) (any, error) {
+			return s.ctrl.Spectrum()
+		},
+		"player.devices": func(json.RawMessage) (any, error) {
+			return s.ctrl.AudioDevices()
+		},
+		"player.setDevice": func(raw json.RawMessage) (any, error) {
+			var p deviceParams
+			if err := decodeParams(raw, &p); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.SetAudioDevice(p.Name)
+		},
+		"player.status": func(json.RawMessage) (any, error) {
+			return s.ctrl.Status()
+		},
+		"queue.add": func(raw json.RawMessage) (any, error) {
+			var videos []player.VideoInfo
+			if err := decodeParams(raw, &videos); err != nil {
+				return nil, err
+			}
+			return nil, s.ctrl.Enqueue(videos...)
+		},
+		"queue.remove": withIndex(s.ctrl.Remove),
+		"queue.list": func(json.RawMessage) (any, error) {
+			return s.ctrl.Queue()
+		},
+		"library.list": func(json.RawMessage) (any, error) {
+			return s.ctrl.Library()
+		},
+		"daemon.shutdown": func(json.RawMessage) (any, error) {
+			s.stopOnce.Do(func() { close(s.done) })
+			return nil, nil
+		},
+	}
+}
+
+type paramsError struct{ error }
+
+func decodeParams(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return paramsError{err}
+	}
+	return nil
+}
+
+type conn struct {
+	net.Conn
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (c *conn) send(v any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.enc.Encode(v)
+}
+
+func (s *Server) serveConn(nc net.Conn) {
+	c := &conn{Conn: nc, enc: json.NewEncoder(nc)}
+	defer c.Close()
+
+	var unsubscribe func()
+	defer func() {
+		if unsubscribe != nil {
+			unsubscribe()
+		}
+	}()
+
+	helloDone := false
+	scanner := bufio.NewScanner(c)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var req rpcRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			c.send(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, err.Error()}})
+			continue
+		}
+
+		switch {
+		case req.Method == "daemon.hello":
+			var p helloParams
+			_ = json.Unmarshal(req.Params, &p)
+			if p.Version != APIVersion {
+				c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{codeVersionError, fmt.Sprintf("unsupported API version %d, daemon speaks %d", p.Version, APIVersion)}})
+				return
+			}
+			helloDone = true
+			c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: helloResult{Version: APIVersion, Name: "ghost_player"}})
+		case !helloDone:
+			c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{codeVersionError, "daemon.hello must be called first"}})
+		case req.Method == "events.subscribe":
+			if unsubscribe == nil {
+				var events <-chan player.PlayerMsg
+				events, unsubscribe = s.ctrl.Subscribe()
+				go forwardEvents(c, events)
+			}
+			c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: true})
+		default:
+			go s.handle(c, req)
+		}
+	}
+}
+
+func (s *Server) handle(c *conn, req rpcRequest) {
+	h, ok := s.handlers[req.Method]
+	if !ok {
+		c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{codeMethodNotFound, "method not found: " + req.Method}})
+		return
+	}
+	result, err := h(req.Params)
+	if len(req.ID) == 0 {
+		return
+	}
+	if err != nil {
+		code := codeServerError
+		if errors.As(err, &paramsError{}) {
+			code = codeInvalidParams
+		}
+		c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{code, err.Error()}})
+		return
+	}
+	if result == nil {
+		result = true
+	}
+	c.send(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func forwardEvents(c *conn, events <-chan player.PlayerMsg) {
+	for msg := range events {
+		e, err := player.EncodeEvent(msg)
+		if err != nil {
+			continue
+		}
+		c.send(rpcNotification{JSONRPC: "2.0", Method: "event", Params: e})
+	}
+}
@@ -0,0 +1,848 @@
+package daemon
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"player/config"
+	"player/equalizer"
+	"player/library"
+	"player/loudness"
+	"player/paths"
+	"player/player"
+	"player/visualizer"
+)
+
+// Status is a snapshot of the playback state shared with every client.
+type Status struct {
+	State  string            `json:"state"`
+	Info   player.PlayerInfo `json:"info"`
+	Track  *player.VideoInfo `json:"track,omitempty"`
+	Index  int               `json:"index"`
+	Volume int               `json:"volume"`
+	// RemoteURL is where the web remote can be reached from the LAN.
+	RemoteURL string    `json:"remote_url,omitempty"`
+	Equalizer []float64 `json:"equalizer"`
+	// Normalization is one of the loudness modes.
+	Normalization string `json:"normalization"`
+	// Crossfade is the overlap between queued tracks in seconds.
+	Crossfade float64 `json:"crossfade"`
+	Speed     float64 `json:"speed"`
+	Shuffle   bool    `json:"shuffle"`
+	Repeat    bool    `json:"repeat"`
+	// Device is the chosen audio output, which may be missing.
+	Device string `json:"device"`
+	// Chapters of the current track, once known.
+	Chapters []player.Chapter `json:"chapters,omitempty"`
+}
+
+// MaxCrossfade is the longest crossfade in seconds.
+const MaxCrossfade = 12
+
+// Controller is the API offered by the daemon. Service implements it
+// in-process and Client implements it over the control socket.
+type Controller interface {
+	Play(video player.VideoInfo) error
+	PlayIndex(index int) error
+	Enqueue(videos ...player.VideoInfo) error
+	Remove(index int) error
+	Queue() ([]player.VideoInfo, error)
+	Next() error
+	Previous() error
+	// StartOver plays the current track again from its start, forgetting
+	// where it was left.
+	StartOver() error
+	NextChapter() error
+	// PreviousChapter restarts the current chapter, or goes to the one
+	// before if it has only just begun.
+	PreviousChapter() error
+	Stop() error
+	TogglePause() error
+	Seek(offset float64) error
+	SetPosition(pos float64) error
+	SetVolume(volume int) error
+	// SetEqualizer applies one gain in dB per equalizer.Frequencies band.
+	SetEqualizer(gains []float64) error
+	SetNormalization(mode string) error
+	// SetCrossfade sets the overlap between queued tracks, 0 to disable.
+	SetCrossfade(seconds float64) error
+	// SetSpeed sets the playback rate and remembers it for the uploader of
+	// the current track.
+	SetSpeed(speed float64) error
+	// SetShuffle plays the queue in random order.
+	SetShuffle(enabled bool) error
+	// SetRepeat starts the queue over once its last track ended.
+	SetRepeat(enabled bool) error
+	// SetVisualizer tells that a client starts or stops showing the
+	// spectrum; the audio is only analysed while one does.
+	SetVisualizer(enabled bool) error
+	// Spectrum returns the visualizer.Bands levels at the playback
+	// position, nil while nothing is measured.
+	Spectrum() ([]float64, error)
+	AudioDevices() ([]player.AudioDevice, error)
+	// SetAudioDevice switches the audio output, player.AutoDevice for the
+	// default one.
+	SetAudioDevice(name string) error
+	Status() (Status, error)
+	Library() ([]library.Record, error)
+	Subscribe() (<-chan player.PlayerMsg, func())
+}
+
+var (
+	_ Controller = (*Service)(nil)
+	_ Controller = (*Client)(nil)
+)
+
+// Service owns the player, the queue and the library.
+type Service struct {
+	mu      sync.Mutex
+	player  *player.Player
+	queue   *player.Queue
+	library *library.Library
+
+	bus *player.Bus
+
+	remoteURL string
+	equalizer []float64
+
+	normalization loudness.Mode
+	// analyzing holds the IDs being measured, so a replayed track is not
+	// measured twice at once.
+	analyzing map[string]bool
+	// stopMeasure cancels the measure of the current track, nil if none
+	// runs.
+	stopMeasure context.CancelFunc
+
+	crossfade float64
+	// device is the chosen audio output. The player falls back to
+	// player.AutoDevice while it is missing.
+	device string
+	// longTrack is the length in seconds from which the position in a
+	// track is kept in the library, 0 to keep none.
+	longTrack float64
+	// chapters are those of the track chaptersOf, the current one.
+	chapters   []player.Chapter
+	chaptersOf string
+	// visualizers counts the clients showing the spectrum of tap.
+	visualizers int
+	tap         visualizer.Tap
+}
+
+// DefaultLongTrack is the length in seconds from which playback resumes
+// where a track was left, unless configured otherwise.
+const DefaultLongTrack = 20 * 60
+
+func NewService(p *player.Player, lib *library.Library) *Service {
+	s := &Service{
+		player:  p,
+		queue:   player.NewQueue(),
+		library: lib,
+		bus:     player.NewBus(),
+
+		equalizer: equalizer.Normalize(nil),
+
+		normalization: loudness.Off,
+		analyzing:     make(map[string]bool),
+		device:        player.AutoDevice,
+		longTrack:     DefaultLongTrack,
+	}
+	// Subscribe before returning so no message of a first Play is missed.
+	events, _ := p.Subscribe()
+	go s.run(events)
+	return s
+}
+
+func (s *Service) run(events <-chan player.PlayerMsg) {
+	for msg := range events {
+		switch msg := msg.(type) {
+		case player.PlayerEndingMsg:
+			go s.crossfadeNext(msg.Remaining)
+			continue
+		case player.TrackAdvancedMsg:
+			go s.advanced(msg.Video)
+			continue
+		case player.AudioDevicesChangedMsg:
+			// The chosen device may have come back, or gone.
+			go s.applyDevice()
+			continue
+		case player.PlayerProgressMsg:
+			s.mu.Lock()
+			s.keepPosition(player.PlayerInfo(msg))
+			s.mu.Unlock()
+			if msg.Progress == 100 {
+				go s.advance()
+			}
+		case player.PlayErrorMsg:
+			s.publish(msg)
+			var pe *player.PlaybackError
+			if errors.As(msg.Err, &pe) && s.queue.MarkFailed(pe.VideoID, pe.Kind) {
+				s.publish(s.queue.Changed())
+			}
+			continue
+		case player.PlayerStateChangedMsg:
+			s.publish(msg)
+			// A track that plays after a retry is no longer failed.
+			if video, ok := s.queue.Current(); ok && string(msg) == player.StateName(player.Playing) &&
+				s.queue.MarkFailed(video.ID, "") {
+				s.publish(s.queue.Changed())
+			}
+			continue
+		}
+		s.publish(msg)
+	}
+}
+
+// advance starts the next queued track once the current one has ended.
+func (s *Service) advance() {
+	if next := s.queue.Following(); next >= 0 {
+		_ = s.PlayIndex(next)
+	}
+}
+
+// crossfadeNext plays the next queued track over the last seconds of the
+// current one. Tracks of the same album or playlist are left to the
+// preloaded playlist of mpv, which plays them without a gap.
+func (s *Service) crossfadeNext(remaining float64) {
+	index, next := s.queue.Index(), s.queue.Following()
+	items := s.queue.Items()
+	if index < 0 || next < 0 || next == index+1 && items[index].SameRelease(items[next]) {
+		return
+	}
+	s.mu.Lock()
+	if s.crossfade == 0 || s.player.State() != player.Playing {
+		s.mu.Unlock()
+		return
+	}
+	s.player.CrossfadeNext(min(s.crossfade, remaining))
+	s.mu.Unlock()
+	if err := s.PlayIndex(next); err != nil {
+		logger("daemon").Warn("crossfade failed", "err", err)
+	}
+	// A next track that failed leaves the current one playing to its end.
+	if s.player.Current() == items[index].ID {
+		if _, err := s.queue.Select(index); err == nil {
+			s.publish(s.queue.Changed())
+		}
+	}
+}
+
+func (s *Service) publish(msg player.PlayerMsg) {
+	s.bus.Publish(msg)
+}
+
+func (s *Service) Subscribe() (<-chan player.PlayerMsg, func()) {
+	return s.bus.Subscribe()
+}
+
+// ForwardLogs publishes the log records of this process to subscribers.
+func (s *Service) ForwardLogs() {
+	go forwardLogs(s.bus)
+}
+
+func (s *Service) Play(video player.VideoInfo) error {
+	index := s.queue.IndexOf(video.ID)
+	if index < 0 {
+		s.queue.Add(video)
+		index = s.queue.Len() - 1
+	}
+	return s.PlayIndex(index)
+}
+
+func (s *Service) PlayIndex(index int) error {
+	video, err := s.queue.Select(index)
+	if err != nil {
+		return err
+	}
+	video.Failed = ""
+	s.publish(s.queue.Changed())
+	return s.start(video)
+}
+
+// start plays video, from where it was left if it is long.
+func (s *Service) start(video player.VideoInfo) error {
+	s.mu.Lock()
+	s.keepPosition(s.player.Info())
+	s.mu.Unlock()
+	left := s.leftAt(video.ID)
+	if left > 0 {
+		logger("daemon").Info("resuming", "video", video.ID, "position", left)
+	}
+	return s.load(video, false, left)
+}
+
+// load plays video from position, or with resume loads it paused there
+// without counting a play.
+func (s *Service) load(video player.VideoInfo, resume bool, position float64) error {
+	s.applyDevice()
+	s.mu.Lock()
+	s.cancelMeasure()
+	measure := s.applyNormalization(video)
+	speed := s.player.Speed()
+	if err := s.setSpeed(s.uploaderSpeed(video)); err != nil {
+		logger("daemon").Warn("setting speed failed", "video", video.ID, "err", err)
+	}
+	speedChanged := s.player.Speed() != speed
+	video.Chapters = s.knownChapters(video)
+	s.chapters, s.chaptersOf = video.Chapters, video.ID
+	s.mu.Unlock()
+
+	// Resolving the stream takes seconds; the other calls, Stop among
+	// them, must not wait for it.
+	var err error
+	switch {
+	case resume:
+		err = s.player.Resume(video, position)
+	case position > 0:
+		err = s.player.PlayFrom(video, position)
+	default:
+		err = s.player.PlayCmd(video)
+	}
+	if err != nil {
+		// The failure reaches the listeners as a PlayErrorMsg; a track
+		// that did not play is neither measured nor counted.
+		logger("daemon").Debug("track did not start", "video", video.ID, "err", err)
+		return nil
+	}
+	s.started(video, measure, !resume)
+	if speedChanged {
+		s.publish(player.SpeedChangedMsg(s.player.Speed()))
+	}
+	return nil
+}
+
+// advanced follows the player onto the track it preloaded, as if it had
+// been started.
+func (s *Service) advanced(video player.VideoInfo) {
+	if index := s.queue.IndexOf(video.ID); index >= 0 {
+		if _, err := s.queue.Select(index); err == nil {
+			s.publish(s.queue.Changed())
+		}
+	}
+	s.mu.Lock()
+	s.cancelMeasure()
+	measure := s.applyNormalization(video)
+	video.Chapters = s.knownChapters(video)
+	s.chapters, s.chaptersOf = video.Chapters, video.ID
+	s.mu.Unlock()
+	s.started(video, measure, true)
+}
+
+// started follows a track once it plays: it is measured if it has to be,
+// counted, and announced with its chapters. The next track is preloaded if
+// it continues the same release.
+func (s *Service) started(video player.VideoInfo, measure, count bool) {
+	s.mu.Lock()
+	stream := s.player.Stream()
+	if chapters := s.player.Chapters(); len(chapters) > 0 {
+		video.Chapters = chapters
+	}
+	if s.chaptersOf == video.ID {
+		s.chapters = video.Chapters
+	}
+	if measure {
+		measure = !s.analyzing[video.ID]
+		s.analyzing[video.ID] = true
+	}
+	var ctx context.Context
+	if measure {
+		s.cancelMeasure()
+		ctx, s.stopMeasure = context.WithTimeout(context.Background(), measureTimeout)
+	}
+	s.mu.Unlock()
+
+	if measure {
+		go s.measure(ctx, video, stream)
+	}
+	if s.library != nil && count {
+		_ = s.library.RecordPlay(video)
+	}
+	s.publish(player.PlayStartedMsg{VideoID: video.ID, Title: video.Title})
+	if len(video.Chapters) > 0 {
+		s.publish(player.ChaptersMsg{VideoID: video.ID, Chapters: video.Chapters})
+	}
+	go s.preloadNext()
+}
+
+// preloadNext hands the next queued track to the player when it continues
+// the release of the current one, so that mpv plays the two without a gap.
+func (s *Service) preloadNext() {
+	index, next := s.queue.Index(), s.queue.Following()
+	items := s.queue.Items()
+	if index < 0 || next != index+1 || !items[index].SameRelease(items[next]) {
+		return
+	}
+	if err := s.player.Preload(items[next]); err != nil {
+		logger("daemon").Debug("preloading failed", "video", items[next].ID, "err", err)
+	}
+}
+
+func (s *Service) Enqueue(videos ...player.VideoInfo) error {
+	s.queue.Add(videos...)
+	s.publish(s.queue.Changed())
+	return nil
+}
+
+func (s *Service) Remove(index int) error {
+	if err := s.queue.Remove(index); err != nil {
+		return err
+	}
+	s.publish(s.queue.Changed())
+	return nil
+}
+
+func (s *Service) Queue() ([]player.VideoInfo, error) {
+	return s.queue.Items(), nil
+}
+
+func (s *Service) Next() error {
+	next := s.queue.Following()
+	if next < 0 {
+		next = s.queue.Index() + 1
+	}
+	return s.PlayIndex(next)
+}
+
+func (s *Service) Previous() error {
+	return s.PlayIndex(s.queue.Index() - 1)
+}
+
+func (s *Service) StartOver() error {
+	video, err := s.queue.Select(s.queue.Index())
+	if err != nil {
+		return err
+	}
+	if err := s.load(video, false, 0); err != nil {
+		return err
+	}
+	// Forgotten once restarted, so no late progress of the track left
+	// brings it back.
+	if s.library == nil {
+		return nil
+	}
+	if _, ok := s.library.Get(video.ID); ok {
+		if err := s.library.Update(video, func(r *library.Record) { r.Position = 0 }); err != nil {
+			logger("daemon").Error("forgetting position failed", "video", video.ID, "err", err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keepPosition(s.player.Info())
+	s.cancelMeasure()
+	if err := s.player.Stop(); err != nil {
+		return err
+	}
+	s.publish(player.PlayStoppedMsg{})
+	return nil
+}
+
+func (s *Service) TogglePause() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.player.TogglePause()
+}
+
+func (s *Service) Seek(offset float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.player.Seek(offset)
+}
+
+func (s *Service) SetPosition(pos float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.player.SetPosition(pos)
+}
+
+func (s *Service) SetVolume(volume int) error {
+	s.mu.Lock()
+	err := s.player.SetVolume(volume)
+	volume = s.player.Volume()
+	s.mu.Unlock()
+	s.publish(player.VolumeChangedMsg(volume))
+	return err
+}
+
+func (s *Service) SetSpeed(speed float64) error {
+	s.mu.Lock()
+	err := s.setSpeed(speed)
+	speed = s.player.Speed()
+	video, ok := s.queue.Current()
+	s.mu.Unlock()
+	if ok && s.library != nil && video.Uploader != "" {
+		if err := s.library.Update(video, func(r *library.Record) { r.Speed = speed }); err != nil {
+			logger("daemon").Error("saving speed failed", "uploader", video.Uploader, "err", err)
+		}
+	}
+	s.publish(player.SpeedChangedMsg(speed))
+	return err
+}
+
+// setSpeed changes the playback rate, keeping the pitch with scaletempo2
+// away from real time. s.mu must be held.
+func (s *Service) setSpeed(speed float64) error {
+	if err := s.player.SetSpeed(speed); err != nil {
+		return err
+	}
+	filter := ""
+	if s.player.Speed() != 1 {
+		filter = "scaletempo2"
+	}
+	return s.player.SetFilter("speed", filter)
+}
+
+// uploaderSpeed is the rate remembered for the uploader of video, real time
+// for unknown ones.
+func (s *Service) uploaderSpeed(video player.VideoInfo) float64 {
+	if s.library == nil || video.Uploader == "" {
+		return 1
+	}
+	if speed, ok := s.library.Speed(video.Uploader); ok {
+		return speed
+	}
+	return 1
+}
+
+// keepPosition records in the library where the long track of info is,
+// or that it was played through. Callers hold s.mu.
+func (s *Service) keepPosition(info player.PlayerInfo) {
+	if s.library == nil || info.VideoID == "" || s.longTrack <= 0 || info.Length < s.longTrack {
+		return
+	}
+	if err := s.library.SavePosition(info.VideoID, info.Position, info.Length); err != nil {
+		logger("daemon").Error("saving position failed", "video", info.VideoID, "err", err)
+	}
+}
+
+// leftAt is where the track id was left, 0 to play it from the start.
+func (s *Service) leftAt(id string) float64 {
+	if s.library == nil || s.longTrack <= 0 {
+		return 0
+	}
+	record, ok := s.library.Get(id)
+	if !ok {
+		return 0
+	}
+	return record.Position
+}
+
+// applyNormalization sets the loudness filters for video and reports
+// whether it still has to be measured. s.mu must be held.
+func (s *Service) applyNormalization(video player.VideoInfo) bool {
+	replaygain, filter, measure := "no", "", false
+	switch {
+	case s.normalization == loudness.Off:
+	case s.normalization == loudness.Dynamic:
+		filter = loudness.LiveFilter
+	case video.IsLocal():
+		replaygain = string(s.normalization)
+	default:
+		if m := s.loudness(video.ID); m != nil {
+			filter = m.Filter()
+		} else {
+			filter = loudness.LiveFilter
+			measure = s.library != nil
+		}
+	}
+	if err := s.player.SetReplayGain(replaygain); err != nil {
+		logger("daemon").Warn("setting replaygain failed", "err", err)
+	}
+	if err := s.player.SetFilter("norm", filter); err != nil {
+		logger("daemon").Warn("setting normalization failed", "err", err)
+	}
+	return measure
+}
+
+func (s *Service) loudness(id string) *loudness.Measurement {
+	if s.library == nil {
+		return nil
+	}
+	record, ok := s.library.Get(id)
+	if !ok {
+		return nil
+	}
+	return record.Loudness
+}
+
+const (
+	// measureSeconds caps how much of a stream is decoded a second time
+	// to measure it; the start of a long track stands for the rest.
+	measureSeconds = 10 * 60
+	measureTimeout = 10 * time.Minute
+)
+
+// measure analyses a stream in the background; the gain is used from its
+// next play on, changing it mid-track would be audible. It stops when
+// another track plays.
+func (s *Service) measure(ctx context.Context, video player.VideoInfo, stream string) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.analyzing, video.ID)
+		s.mu.Unlock()
+	}()
+	m, err := loudness.Analyze(ctx, stream, measureSeconds)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		logger("daemon").Debug("measuring loudness stopped", "video", video.ID)
+		return
+	}
+	if err != nil {
+		logger("daemon").Warn("measuring loudness failed", "video", video.ID, "err", err)
+		return
+	}
+	if err := s.library.Update(video, func(r *library.Record) { r.Loudness = &m }); err != nil {
+		logger("daemon").Error("saving loudness failed", "video", video.ID, "err", err)
+	}
+}
+
+// cancelMeasure stops the measure of the current track. s.mu must be held.
+func (s *Service) cancelMeasure() {
+	if s.stopMeasure != nil {
+		s.stopMeasure()
+		s.stopMeasure = nil
+	}
+}
+
+// knownChapters are the chapters of video found when it was last played.
+func (s *Service) knownChapters(video player.VideoInfo) []player.Chapter {
+	if len(video.Chapters) > 0 || s.library == nil {
+		return video.Chapters
+	}
+	r, _ := s.library.Get(video.ID)
+	return r.Video.Chapters
+}
+
+func (s *Service) NextChapter() error {
+	return s.seekChapter(player.NextChapter)
+}
+
+func (s *Service) PreviousChapter() error {
+	return s.seekChapter(player.PreviousChapter)
+}
+
+// seekChapter moves to the chapter start found by find, if any.
+func (s *Service) seekChapter(find func([]player.Chapter, float64) (float64, bool)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.chapters) == 0 {
+		return errors.New("the track has no chapters")
+	}
+	start, ok := find(s.chapters, s.player.Info().Position)
+	if !ok {
+		return nil
+	}
+	return s.player.SetPosition(start)
+}
+
+func (s *Service) SetNormalization(mode string) error {
+	m, err := loudness.ParseMode(mode)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.normalization = m
+	if video, ok := s.queue.Current(); ok && s.player.State() != player.Stopped {
+		s.applyNormalization(video)
+	}
+	return nil
+}
+
+// SetCrossfade sets the crossfade in seconds, clamped to 0-MaxCrossfade.
+func (s *Service) SetCrossfade(seconds float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crossfade = max(0, min(seconds, MaxCrossfade))
+	s.player.SetCrossfade(s.crossfade)
+	return nil
+}
+
+func (s *Service) SetShuffle(enabled bool) error {
+	s.queue.SetShuffle(enabled)
+	return nil
+}
+
+func (s *Service) SetRepeat(enabled bool) error {
+	s.queue.SetRepeat(enabled)
+	return nil
+}
+
+// Configure applies the playback settings of the user configuration.
+func (s *Service) Configure(cfg config.Config) {
+	_ = s.SetCrossfade(cfg.Crossfade)
+	s.mu.Lock()
+	s.longTrack = cmp.Or(cfg.Session.LongTrack, DefaultLongTrack)
+	s.mu.Unlock()
+	if cfg.Normalization != "" {
+		if err := s.SetNormalization(cfg.Normalization); err != nil {
+			logger("daemon").Warn("invalid normalization in config", "err", err)
+		}
+	}
+	if len(cfg.Equalizer.Gains) > 0 {
+		if err := s.SetEqualizer(cfg.Equalizer.Gains); err != nil {
+			logger("daemon").Warn("invalid equalizer in config", "err", err)
+		}
+	}
+	if cfg.AudioDevice != "" {
+		s.mu.Lock()
+		s.device = cfg.AudioDevice
+		s.mu.Unlock()
+		s.applyDevice()
+	}
+	if err := s.player.SetFormat(cfg.Format); err != nil {
+		logger("daemon").Warn("invalid format in config", "err", err)
+	}
+	if cfg.Ytdlp.Managed {
+		go func() {
+			if _, err := player.InstallYtdlp(context.Background()); err != nil {
+				logger("daemon").Error("installing yt-dlp failed", "err", err)
+			}
+		}()
+	}
+}
+
+func (s *Service) AudioDevices() ([]player.AudioDevice, error) {
+	return s.player.AudioDevices()
+}
+
+func (s *Service) SetAudioDevice(name string) error {
+	if name == "" {
+		name = player.AutoDevice
+	}
+	devices, err := s.player.AudioDevices()
+	if err != nil {
+		return err
+	}
+	if !player.HasDevice(devices, name) {
+		return fmt.Errorf("unknown audio device %q", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.device = name
+	return s.player.SetAudioDevice(name)
+}
+
+// applyDevice plays to the chosen device if it is there, or lets mpv pick
+// until it comes back. It looks at the devices the player knows, so it is
+// cheap on every track.
+func (s *Service) applyDevice() {
+	s.mu.Lock()
+	device := s.device
+	s.mu.Unlock()
+	if device != player.AutoDevice {
+		devices, err := s.player.KnownDevices()
+		if err == nil && !player.HasDevice(devices, device) {
+			logger("daemon").Warn("audio device missing, using the default", "device", device)
+			device = player.AutoDevice
+		}
+	}
+	if device != s.player.AudioDevice() {
+		if err := s.player.SetAudioDevice(device); err != nil {
+			logger("daemon").Warn("setting audio device failed", "device", device, "err", err)
+		}
+	}
+}
+
+func (s *Service) SetEqualizer(gains []float64) error {
+	gains = equalizer.Normalize(gains)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.equalizer = gains
+	return s.player.SetFilter("eq", equalizer.Filter(gains))
+}
+
+func (s *Service) SetVisualizer(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case enabled:
+		s.visualizers++
+	case s.visualizers > 0:
+		s.visualizers--
+	}
+	if s.visualizers == 0 {
+		s.tap.Reset()
+	}
+	return nil
+}
+
+// decodeTimeout bounds the decoding of a chunk of audio for the spectrum.
+const decodeTimeout = 10 * time.Second
+
+func (s *Service) Spectrum() ([]float64, error) {
+	s.mu.Lock()
+	shown := s.visualizers > 0
+	video, ok := s.queue.Current()
+	s.mu.Unlock()
+	if !shown || !ok {
+		return nil, nil
+	}
+	position, err := s.player.TimePos()
+	if err != nil {
+		return nil, err
+	}
+	return s.tap.Levels(video.ID, position, func(start, end float64) ([]float32, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), decodeTimeout)
+		defer cancel()
+		if video.IsLocal() {
+			return visualizer.Decode(ctx, video.Path, start, end-start)
+		}
+		// The stream is decoded from the cache of mpv, not fetched twice.
+		dump, err := os.CreateTemp(paths.RuntimeDir(), "spectrum-*.mkv")
+		if err != nil {
+			return nil, err
+		}
+		dump.Close()
+		defer os.Remove(dump.Name())
+		if err := s.player.DumpCache(start, end, dump.Name()); err != nil {
+			return nil, err
+		}
+		return visualizer.Decode(ctx, dump.Name(), 0, end-start)
+	}), nil
+}
+
+func (s *Service) Status() (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		State:     player.StateName(s.player.State()),
+		Info:      s.player.Info(),
+		Index:     s.queue.Index(),
+		Volume:    s.player.Volume(),
+		RemoteURL: s.remoteURL,
+		Equalizer: slices.Clone(s.equalizer),
+
+		Normalization: string(s.normalization),
+		Crossfade:     s.crossfade,
+		Speed:         s.player.Speed(),
+		Device:        s.device,
+		Chapters:      slices.Clone(s.chapters),
+	}
+	status.Shuffle, status.Repeat = s.queue.Modes()
+	if video, ok := s.queue.Current(); ok {
+		status.Track = &video
+	}
+	return status, nil
+}
+
+// SetRemoteURL records where the web remote is served, for Status.
+func (s *Service) SetRemoteURL(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remoteURL = url
+}
+
+func (s *Service) Library() ([]library.Record, error) {
+	if s.library == nil {
+		return nil, nil
+	}
+	return s.library.Records(), nil
+}
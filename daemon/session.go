@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"player/paths"
+	"player/player"
+)
+
+// SessionInterval is how often the session is saved while the daemon runs.
+const SessionInterval = 30 * time.Second
+
+// Session is the playback state kept across restarts.
+type Session struct {
+	Queue []player.VideoInfo `json:"queue"`
+	// Index is the current track in Queue, -1 for none.
+	Index int `json:"index"`
+	// Position is where the current track was left, in seconds.
+	Position float64 `json:"position"`
+	Volume   int     `json:"volume"`
+	Shuffle  bool    `json:"shuffle"`
+	Repeat   bool    `json:"repeat"`
+}
+
+func SessionPath() string {
+	return filepath.Join(paths.StateDir(), "session.json")
+}
+
+// LoadSession reads the session at path. A missing file yields an empty
+// session.
+func LoadSession(path string) (Session, error) {
+	sess := Session{Index: -1}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sess, nil
+	}
+	if err != nil {
+		return sess, err
+	}
+	err = json.Unmarshal(data, &sess)
+	return sess, err
+}
+
+// Save writes the session at path, replacing the previous one at once.
+func (sess Session) Save(path string) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Snapshot returns the current session.
+func (s *Service) Snapshot() Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := Session{
+		Queue:  s.queue.Items(),
+		Index:  s.queue.Index(),
+		Volume: s.player.Volume(),
+	}
+	sess.Shuffle, sess.Repeat = s.queue.Modes()
+	if state := s.player.State(); state == player.Playing || state == player.Paused {
+		sess.Position = s.player.Info().Position
+	}
+	return sess
+}
+
+// Restore puts back the queue, current track, volume and modes of sess.
+// With resume the current track is loaded paused where it was left.
+func (s *Service) Restore(sess Session, resume bool) error {
+	s.queue.SetShuffle(sess.Shuffle)
+	s.queue.SetRepeat(sess.Repeat)
+	if sess.Volume > 0 {
+		s.mu.Lock()
+		err := s.player.SetVolume(sess.Volume)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	if len(sess.Queue) == 0 {
+		return nil
+	}
+	for i := range sess.Queue {
+		sess.Queue[i].Failed = ""
+	}
+	s.queue.Add(sess.Queue...)
+	video, err := s.queue.Select(sess.Index)
+	s.publish(s.queue.Changed())
+	if err != nil || !resume {
+		return nil
+	}
+	return s.load(video, true, sess.Position)
+}
+
+// KeepSession saves the session at path every interval when it changed.
+// The returned stop saves it one last time.
+func (s *Service) KeepSession(path string, interval time.Duration) (stop func()) {
+	var last []byte
+	save := func() {
+		sess := s.Snapshot()
+		data, err := json.Marshal(sess)
+		if err != nil || bytes.Equal(data, last) {
+			return
+		}
+		if err := sess.Save(path); err != nil {
+			logger("daemon").Error("saving session failed", "err", err)
+			return
+		}
+		last = data
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				save()
+			case <-done:
+				save()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
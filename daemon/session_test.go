@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"player/player"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	if sess, err := LoadSession(path); err != nil || sess.Index != -1 || len(sess.Queue) != 0 {
+		t.Fatalf("LoadSession(missing) = %+v, %v, want an empty session", sess, err)
+	}
+
+	want := Session{
+		Queue:    []player.VideoInfo{{ID: "a", Title: "A"}, {ID: "b", Title: "B"}},
+		Index:    1,
+		Position: 42.5,
+		Volume:   35,
+		Shuffle:  true,
+		Repeat:   true,
+	}
+	if err := want.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err := LoadSession(path)
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadSession() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRestoreSession(t *testing.T) {
+	svc := NewService(player.NewPlayer(), nil)
+	sess := Session{
+		Queue: []player.VideoInfo{
+			{ID: "a", Title: "A"},
+			{ID: "b", Title: "B", Failed: player.ErrNetwork},
+		},
+		Index:    1,
+		Position: 12,
+		Volume:   35,
+		Repeat:   true,
+	}
+	if err := svc.Restore(sess, false); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	status, _ := svc.Status()
+	if status.Index != 1 || status.Volume != 35 || status.State != player.StateName(player.Stopped) {
+		t.Errorf("status = index %d, volume %d, %s, want index 1, volume 35, stopped", status.Index, status.Volume, status.State)
+	}
+	if status.Shuffle || !status.Repeat {
+		t.Errorf("status modes = shuffle %v, repeat %v, want repeat only", status.Shuffle, status.Repeat)
+	}
+	if status.Track == nil || status.Track.ID != "b" || status.Track.Failed != "" {
+		t.Errorf("current track = %+v, want b without failure", status.Track)
+	}
+
+	snapshot := svc.Snapshot()
+	if len(snapshot.Queue) != 2 || snapshot.Index != 1 || snapshot.Volume != 35 || snapshot.Position != 0 || !snapshot.Repeat {
+		t.Errorf("Snapshot() = %+v, want the restored queue and modes without position", snapshot)
+	}
+}
+
+func TestKeepSessionSavesOnStop(t *testing.T) {
+	svc := NewService(player.NewPlayer(), nil)
+	path := filepath.Join(t.TempDir(), "session.json")
+	stop := svc.KeepSession(path, time.Hour)
+	_ = svc.Enqueue(player.VideoInfo{ID: "a"})
+	stop()
+
+	sess, err := LoadSession(path)
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+	if len(sess.Queue) != 1 || sess.Queue[0].ID != "a" {
+		t.Errorf("saved queue = %+v, want [a]", sess.Queue)
+	}
+}
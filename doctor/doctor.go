@@ -0,0 +1,218 @@
+// Package doctor checks the programs the player depends on and tells how
+// to fix what is missing.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"player/player"
+)
+
+// checkTimeout bounds each program run by a check.
+const checkTimeout = 5 * time.Second
+
+// Check is the state of one dependency. Problem is empty when it is usable.
+type Check struct {
+	Name string
+	// Required dependencies are needed to play anything; the others enable
+	// some features.
+	Required bool
+	Path     string
+	Version  string
+	Problem  string
+	// Warning is a problem that leaves the dependency usable, like an old
+	// version. Fix tells how to solve either.
+	Warning string
+	Fix     string
+}
+
+func (c Check) OK() bool {
+	return c.Problem == ""
+}
+
+// Run checks mpv, yt-dlp and ffmpeg.
+func Run(ctx context.Context) []Check {
+	return []Check{checkMpv(ctx), checkYtdlp(ctx), checkFfmpeg(ctx)}
+}
+
+// Warned returns the usable dependencies that have a warning.
+func Warned(checks []Check) []Check {
+	var warned []Check
+	for _, c := range checks {
+		if c.OK() && c.Warning != "" {
+			warned = append(warned, c)
+		}
+	}
+	return warned
+}
+
+// Missing returns the required dependencies that are not usable.
+func Missing(checks []Check) []Check {
+	var missing []Check
+	for _, c := range checks {
+		if c.Required && !c.OK() {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// Report writes one line per check, followed by how to fix it if needed.
+func Report(w io.Writer, checks []Check) {
+	for _, c := range checks {
+		mark := "✓"
+		if c.Warning != "" {
+			mark = "!"
+		}
+		if !c.OK() {
+			mark = "✗"
+			if !c.Required {
+				mark = "!"
+			}
+		}
+		line := fmt.Sprintf("%s %-7s", mark, c.Name)
+		if c.Version != "" {
+			line += " " + c.Version
+		}
+		if c.Path != "" {
+			line += " (" + c.Path + ")"
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+		switch {
+		case !c.OK():
+			fmt.Fprintf(w, "  %s\n  fix: %s\n", c.Problem, c.Fix)
+		case c.Warning != "":
+			fmt.Fprintf(w, "  %s\n  fix: %s\n", c.Warning, c.Fix)
+		}
+	}
+}
+
+func packageHint(pkg string) string {
+	return fmt.Sprintf("install %[1]s with your package manager, e.g. apt install %[1]s, dnf install %[1]s, pacman -S %[1]s or brew install %[1]s", pkg)
+}
+
+func checkMpv(ctx context.Context) Check {
+	c := Check{Name: "mpv", Required: true}
+	path, err := exec.LookPath("mpv")
+	if err != nil {
+		c.Problem = "mpv is not installed or not on PATH"
+		c.Fix = packageHint("mpv")
+		return c
+	}
+	c.Path = path
+	out, err := run(ctx, path, "--version")
+	if err != nil {
+		c.Problem = fmt.Sprintf("mpv does not run: %v", err)
+		c.Fix = "reinstall mpv"
+		return c
+	}
+	// "mpv 0.37.0 Copyright © 2000-2023 mpv/MPlayer/mplayer2 projects"
+	c.Version = field(out, 1)
+
+	options, err := run(ctx, path, "--list-options")
+	if err != nil || !strings.Contains(options, "--input-ipc-server") {
+		c.Problem = "mpv is built without JSON IPC, needed for pause, seek and volume"
+		c.Fix = "install an mpv build with IPC support, version 0.7 or later"
+	}
+	return c
+}
+
+func checkYtdlp(ctx context.Context) Check {
+	c := Check{Name: "yt-dlp", Required: true}
+	path := player.ManagedYtdlp()
+	if _, err := exec.LookPath(path); err != nil {
+		if path, err = exec.LookPath("yt-dlp"); err != nil {
+			c.Problem = "yt-dlp is not installed or not on PATH"
+			c.Fix = installHint()
+			return c
+		}
+	}
+	c.Path = path
+	out, err := run(ctx, path, "--version")
+	if err != nil {
+		c.Problem = fmt.Sprintf("yt-dlp does not run: %v", err)
+		c.Fix = installHint()
+		return c
+	}
+	c.Version = field(out, 0)
+	if olderRelease(c.Version, player.YtdlpVersion) {
+		c.Warning = fmt.Sprintf("yt-dlp %s is older than %s and may fail on YouTube", c.Version, player.YtdlpVersion)
+		c.Fix = installHint()
+	}
+	return c
+}
+
+// olderRelease reports whether the yt-dlp release version predates pinned.
+// Releases are named after their date, like 2024.08.06 or 2024.8.6, with
+// the time after it for nightly builds. Versions that do not parse are not
+// taken for older.
+func olderRelease(version, pinned string) bool {
+	v, ok := releaseDate(version)
+	p, pinnedOK := releaseDate(pinned)
+	return ok && pinnedOK && slices.Compare(v, p) < 0
+}
+
+func releaseDate(version string) ([]int, bool) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 3 {
+		return nil, false
+	}
+	date := make([]int, 3)
+	for i := range date {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return nil, false
+		}
+		date[i] = n
+	}
+	return date, true
+}
+
+func installHint() string {
+	return fmt.Sprintf("run `ghost_player doctor -install-ytdlp` to install yt-dlp %s in %s, or set \"ytdlp\": {\"managed\": true} in the config to keep it updated",
+		player.YtdlpVersion, player.ManagedYtdlp())
+}
+
+func checkFfmpeg(ctx context.Context) Check {
+	c := Check{Name: "ffmpeg"}
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		c.Problem = "ffmpeg is not installed, the visualizer and loudness analysis are disabled"
+		c.Fix = packageHint("ffmpeg")
+		return c
+	}
+	c.Path = path
+	out, err := run(ctx, path, "-version")
+	if err != nil {
+		c.Problem = fmt.Sprintf("ffmpeg does not run: %v", err)
+		c.Fix = "reinstall ffmpeg"
+		return c
+	}
+	// "ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers"
+	c.Version = field(out, 2)
+	return c
+}
+
+func run(ctx context.Context, bin string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, args...).Output()
+	return string(out), err
+}
+
+// field is the nth word of the first line of out.
+func field(out string, n int) string {
+	line, _, _ := strings.Cut(out, "\n")
+	fields := strings.Fields(line)
+	if n < len(fields) {
+		return fields[n]
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"player/player"
+)
+
+// fakeBin installs a script printing output as name in dir. PATH may
+// hold nothing else, so it only uses shell builtins.
+func fakeBin(t *testing.T, dir, name, output string) {
+	t.Helper()
+	script := "#!/bin/sh\necho '" + output + "'\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunFindsDependencies(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	fakeBin(t, bin, "mpv", "mpv 0.37.0 Copyright © 2000-2023 mpv/MPlayer/mplayer2 projects\n --input-ipc-server  String")
+	fakeBin(t, bin, "yt-dlp", "2020.01.01")
+
+	checks := Run(context.Background())
+	if len(checks) != 3 {
+		t.Fatalf("got %d checks, want 3", len(checks))
+	}
+	mpv, ytdlp, ffmpeg := checks[0], checks[1], checks[2]
+	if !mpv.OK() || mpv.Version != "0.37.0" {
+		t.Errorf("mpv = %+v, want version 0.37.0 without problem", mpv)
+	}
+	if !ytdlp.OK() || ytdlp.Warning == "" || ytdlp.Version != "2020.01.01" || !strings.Contains(ytdlp.Fix, "-install-ytdlp") {
+		t.Errorf("yt-dlp = %+v, want a usable outdated version with the install hint", ytdlp)
+	}
+	if ffmpeg.OK() || ffmpeg.Required {
+		t.Errorf("ffmpeg = %+v, want an optional missing dependency", ffmpeg)
+	}
+	if missing := Missing(checks); len(missing) != 0 {
+		t.Errorf("missing = %+v, want none", missing)
+	}
+	if warned := Warned(checks); len(warned) != 1 || warned[0].Name != "yt-dlp" {
+		t.Errorf("warned = %+v, want yt-dlp only", warned)
+	}
+}
+
+func TestOlderRelease(t *testing.T) {
+	tests := []struct {
+		version, pinned string
+		want            bool
+	}{
+		{"2024.8.6", "2024.10.07", true},
+		{"2024.10.7", "2024.08.06", false},
+		{"2024.08.06.232705", "2024.08.06", false},
+		{"2023.12.30", "2024.01.01", true},
+		{"unknown", "2024.01.01", false},
+	}
+	for _, tt := range tests {
+		if got := olderRelease(tt.version, tt.pinned); got != tt.want {
+			t.Errorf("olderRelease(%q, %q) = %v, want %v", tt.version, tt.pinned, got, tt.want)
+		}
+	}
+}
+
+func TestManagedYtdlpIsPreferred(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	fakeBin(t, bin, "mpv", "mpv 0.37.0\n")
+	fakeBin(t, bin, "yt-dlp", "2020.01.01")
+	managed := player.ManagedYtdlp()
+	if err := os.MkdirAll(filepath.Dir(managed), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	fakeBin(t, filepath.Dir(managed), filepath.Base(managed), player.YtdlpVersion)
+
+	checks := Run(context.Background())
+	if mpv := checks[0]; mpv.OK() || !strings.Contains(mpv.Problem, "IPC") {
+		t.Errorf("mpv = %+v, want the missing IPC support reported", mpv)
+	}
+	if ytdlp := checks[1]; !ytdlp.OK() || ytdlp.Path != managed {
+		t.Errorf("yt-dlp = %+v, want the managed install", ytdlp)
+	}
+}
@@ -0,0 +1,80 @@
+package equalizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Frequencies are the centres of the ten octave bands.
+var Frequencies = []float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+// MaxGain bounds each band, in dB, both ways.
+const MaxGain = 12.0
+
+type Preset struct {
+	Name  string    `json:"name"`
+	Gains []float64 `json:"gains"`
+}
+
+// Presets are always available; user presets from the config come after.
+var Presets = []Preset{
+	{Name: "flat", Gains: make([]float64, len(Frequencies))},
+	{Name: "bass boost", Gains: []float64{6, 5, 4, 2, 0, 0, 0, 0, 0, 0}},
+	{Name: "vocal", Gains: []float64{-2, -2, -1, 1, 3, 4, 3, 1, 0, -1}},
+	{Name: "loudness", Gains: []float64{5, 4, 2, 0, -1, -1, 0, 2, 4, 5}},
+}
+
+// Find looks a preset up by name, case insensitively.
+func Find(presets []Preset, name string) (Preset, bool) {
+	for _, p := range presets {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Normalize returns exactly one gain per band within ±MaxGain; missing
+// bands are flat.
+func Normalize(gains []float64) []float64 {
+	out := make([]float64, len(Frequencies))
+	for i := range out {
+		if i < len(gains) {
+			out[i] = max(-MaxGain, min(gains[i], MaxGain))
+		}
+	}
+	return out
+}
+
+func IsFlat(gains []float64) bool {
+	for _, g := range gains {
+		if g != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter is the mpv audio filter for gains, an empty string when flat.
+func Filter(gains []float64) string {
+	gains = Normalize(gains)
+	if IsFlat(gains) {
+		return ""
+	}
+	bands := make([]string, 0, len(gains))
+	for i, g := range gains {
+		if g == 0 {
+			continue
+		}
+		bands = append(bands, fmt.Sprintf("equalizer=f=%g:t=o:w=1:g=%g", Frequencies[i], g))
+	}
+	return "lavfi=[" + strings.Join(bands, ",") + "]"
+}
+
+// Label names a frequency for display, e.g. 1k.
+func Label(freq float64) string {
+	if freq >= 1000 {
+		return fmt.Sprintf("%gk", freq/1000)
+	}
+	return fmt.Sprintf("%g", freq)
+}
@@ -0,0 +1,25 @@
+package equalizer
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	if got := Filter(nil); got != "" {
+		t.Errorf("Filter(flat) = %q, want empty", got)
+	}
+	got := Filter([]float64{3, 0, 0, 0, 0, -20})
+	want := "lavfi=[equalizer=f=31:t=o:w=1:g=3,equalizer=f=1000:t=o:w=1:g=-12]"
+	if got != want {
+		t.Errorf("Filter = %q, want %q", got, want)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, p := range Presets {
+		if len(p.Gains) != len(Frequencies) {
+			t.Errorf("preset %q has %d bands", p.Name, len(p.Gains))
+		}
+	}
+	if p, ok := Find(Presets, "Bass Boost"); !ok || p.Gains[0] != 6 {
+		t.Errorf("Find(Bass Boost) = %v, %v", p, ok)
+	}
+}
@@ -0,0 +1,390 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"player/daemon"
+	"player/player"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// DefaultAddr only accepts connections from the local machine.
+const DefaultAddr = "127.0.0.1:8765"
+
+// Server serves the REST API under /api/v1, the event stream on
+// /api/v1/events and the web remote on /.
+type Server struct {
+	ctrl  daemon.Controller
+	token string
+	http  *http.Server
+	ln    net.Listener
+
+	// Search looks tracks up for GET /api/v1/search.
+	Search func(query string, maxResults int) ([]player.VideoInfo, error)
+
+	// found are the results of the last search, to name the tracks the
+	// remote picks among them.
+	mu    sync.Mutex
+	found []player.VideoInfo
+}
+
+// Listen starts serving on addr. When token is not empty every request must
+// carry it, either as a bearer token or as the token query parameter. Without
+// a token, requests from other web pages are still refused.
+func Listen(addr, token string, ctrl daemon.Controller) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+	s := &Server{
+		ctrl:   ctrl,
+		token:  token,
+		ln:     ln,
+		Search: player.SearchYoutube,
+	}
+	s.http = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.http.Serve(ln)
+	return s, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.http.Shutdown(ctx)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/status", s.status)
+	mux.HandleFunc("GET /api/v1/search", s.search)
+	mux.HandleFunc("GET /api/v1/queue", s.queue)
+	mux.HandleFunc("POST /api/v1/queue", s.enqueue)
+	mux.HandleFunc("DELETE /api/v1/queue/{index}", s.remove)
+	mux.HandleFunc("POST /api/v1/queue/{index}/play", s.playIndex)
+	mux.HandleFunc("POST /api/v1/player/play", s.play)
+	mux.HandleFunc("POST /api/v1/player/pause", s.action(s.ctrl.TogglePause))
+	mux.HandleFunc("POST /api/v1/player/stop", s.action(s.ctrl.Stop))
+	mux.HandleFunc("POST /api/v1/player/next", s.action(s.ctrl.Next))
+	mux.HandleFunc("POST /api/v1/player/previous", s.action(s.ctrl.Previous))
+	mux.HandleFunc("POST /api/v1/player/seek", s.seek)
+	mux.HandleFunc("PUT /api/v1/player/volume", s.volume)
+	mux.HandleFunc("GET /api/v1/library", s.library)
+	mux.HandleFunc("GET /api/v1/events", s.events)
+
+	root := http.NewServeMux()
+	root.Handle("/api/", s.authenticate(mux))
+	// The remote page holds no data, it asks for the token itself.
+	root.Handle("/", webHandler())
+	return root
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := sameOrigin(r); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+		if s.token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		got := r.URL.Query().Get("token")
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin refuses the requests a web page of another site can make. A
+// browser sends them with the Origin of the page, and without a preflight
+// only with a form Content-Type; a page cannot read the answers of GETs.
+func sameOrigin(r *http.Request) error {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return fmt.Errorf("cross-origin request from %q", origin)
+		}
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return nil
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		return errors.New("Content-Type must be application/json")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// reply answers with v, or with a 500 carrying err.
+func reply(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func pathIndex(w http.ResponseWriter, r *http.Request) (int, bool) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid index %q", r.PathValue("index")))
+		return 0, false
+	}
+	return index, true
+}
+
+func (s *Server) action(fn func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reply(w, nil, fn())
+	}
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	status, err := s.ctrl.Status()
+	reply(w, status, err)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing q parameter"))
+		return
+	}
+	maxResults, err := strconv.Atoi(r.URL.Query().Get("max"))
+	if err != nil || maxResults <= 0 {
+		maxResults = 10
+	}
+	results, err := s.Search(query, min(maxResults, 50))
+	if err == nil {
+		s.mu.Lock()
+		s.found = results
+		s.mu.Unlock()
+	}
+	reply(w, results, err)
+}
+
+// video rebuilds the track a client names by video ID, URL, or the ID of a
+// local file. Clients never give a path: only the files of the library play.
+func (s *Server) video(ref string) (player.VideoInfo, error) {
+	records, err := s.ctrl.Library()
+	if err != nil {
+		return player.VideoInfo{}, err
+	}
+	if file, ok := strings.CutPrefix(ref, "file:"); ok {
+		for _, r := range records {
+			if r.Video.ID == ref {
+				return player.LocalTrack(file)
+			}
+		}
+		return player.VideoInfo{}, fmt.Errorf("%q is not in the library", ref)
+	}
+	video, ok := player.VideoFromURL(ref)
+	if !ok {
+		return player.VideoInfo{}, fmt.Errorf("unsupported video %q", ref)
+	}
+	// What the daemon already knows of the video titles it in the queue.
+	for _, r := range records {
+		if r.Video.ID == video.ID {
+			known := r.Video
+			known.Path = ""
+			return known, nil
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, found := range s.found {
+		if found.ID == video.ID {
+			return found, nil
+		}
+	}
+	video.Title = video.URL
+	return video, nil
+}
+
+func (s *Server) queue(w http.ResponseWriter, r *http.Request) {
+	videos, err := s.ctrl.Queue()
+	if videos == nil {
+		videos = []player.VideoInfo{}
+	}
+	reply(w, videos, err)
+}
+
+// enqueueRequest adds tracks by the IDs search and the library list, or by
+// YouTube URL.
+type enqueueRequest struct {
+	IDs  []string `json:"ids"`
+	URLs []string `json:"urls"`
+}
+
+func (s *Server) enqueue(w http.ResponseWriter, r *http.Request) {
+	var req enqueueRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	var videos []player.VideoInfo
+	for _, ref := range append(req.IDs, req.URLs...) {
+		video, err := s.video(ref)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		videos = append(videos, video)
+	}
+	if len(videos) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("nothing to add"))
+		return
+	}
+	reply(w, nil, s.ctrl.Enqueue(videos...))
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	if index, ok := pathIndex(w, r); ok {
+		reply(w, nil, s.ctrl.Remove(index))
+	}
+}
+
+func (s *Server) playIndex(w http.ResponseWriter, r *http.Request) {
+	if index, ok := pathIndex(w, r); ok {
+		reply(w, nil, s.ctrl.PlayIndex(index))
+	}
+}
+
+// playRequest plays a track by ID or URL, as enqueueRequest names them.
+type playRequest struct {
+	ID string `json:"id"`
+}
+
+func (s *Server) play(w http.ResponseWriter, r *http.Request) {
+	var req playRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.ID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing video id"))
+		return
+	}
+	video, err := s.video(req.ID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	reply(w, nil, s.ctrl.Play(video))
+}
+
+type seekRequest struct {
+	Seconds  float64 `json:"seconds"`
+	Relative bool    `json:"relative"`
+}
+
+func (s *Server) seek(w http.ResponseWriter, r *http.Request) {
+	var req seekRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Relative {
+		reply(w, nil, s.ctrl.Seek(req.Seconds))
+		return
+	}
+	reply(w, nil, s.ctrl.SetPosition(req.Seconds))
+}
+
+type volumeRequest struct {
+	Volume int `json:"volume"`
+}
+
+func (s *Server) volume(w http.ResponseWriter, r *http.Request) {
+	var req volumeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	reply(w, nil, s.ctrl.SetVolume(req.Volume))
+}
+
+func (s *Server) library(w http.ResponseWriter, r *http.Request) {
+	records, err := s.ctrl.Library()
+	reply(w, records, err)
+}
+
+// events upgrades to a WebSocket and pushes every player event as a
+// player.Event JSON object.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+
+	events, unsubscribe := s.ctrl.Subscribe()
+	defer unsubscribe()
+
+	// Reads are only needed to notice the client going away.
+	ctx := conn.CloseRead(r.Context())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-events:
+			if !ok {
+				conn.Close(websocket.StatusGoingAway, "daemon stopping")
+				return
+			}
+			e, err := player.EncodeEvent(msg)
+			if err != nil {
+				continue
+			}
+			writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err = wsjson.Write(writeCtx, conn, e)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
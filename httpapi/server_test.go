@@ -0,0 +1,165 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"player/daemon/daemontest"
+	"player/player"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+func newTestServer(t *testing.T) (*daemontest.Controller, *httptest.Server) {
+	t.Helper()
+	ctrl := daemontest.New()
+	s := &Server{ctrl: ctrl, token: "secret"}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ctrl, ts
+}
+
+func do(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if method != "GET" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestTokenAuth(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	if resp := do(t, "GET", ts.URL+"/api/v1/queue", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := do(t, "GET", ts.URL+"/api/v1/queue", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", resp.StatusCode)
+	}
+	if resp := do(t, "GET", ts.URL+"/api/v1/queue?token=secret", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("query token: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	ctrl, ts := newTestServer(t)
+
+	resp := do(t, "POST", ts.URL+"/api/v1/queue", "secret", `{"urls": ["https://youtu.be/dQw4w9WgXcQ"]}`)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", resp.StatusCode)
+	}
+	if items, _ := ctrl.Queue(); len(items) != 1 || items[0].ID != "dQw4w9WgXcQ" {
+		t.Errorf("queue = %+v", items)
+	}
+
+	resp = do(t, "POST", ts.URL+"/api/v1/queue", "secret", `{"urls": ["not a video"]}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad url: status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestPlayRebuildsTheVideo(t *testing.T) {
+	ctrl, ts := newTestServer(t)
+
+	resp := do(t, "POST", ts.URL+"/api/v1/player/play", "secret", `{"id": "dQw4w9WgXcQ", "path": "/etc/passwd"}`)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", resp.StatusCode)
+	}
+	if items, _ := ctrl.Queue(); len(items) != 1 || items[0].ID != "dQw4w9WgXcQ" || items[0].Path != "" {
+		t.Errorf("queue = %+v, want the video without a path", items)
+	}
+
+	for _, body := range []string{`{"id": "file:/etc/passwd"}`, `{"id": "-o/tmp/x"}`} {
+		if resp := do(t, "POST", ts.URL+"/api/v1/player/play", "secret", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("play %s: status = %d, want 400", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestCrossSiteRequestsAreRefused(t *testing.T) {
+	ctrl := daemontest.New()
+	ts := httptest.NewServer((&Server{ctrl: ctrl}).Handler())
+	t.Cleanup(ts.Close)
+
+	post := func(contentType, origin string) int {
+		req, _ := http.NewRequest("POST", ts.URL+"/api/v1/player/stop", nil)
+		req.Header.Set("Content-Type", contentType)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := post("text/plain", ""); status != http.StatusForbidden {
+		t.Errorf("text/plain: status = %d, want 403", status)
+	}
+	if status := post("application/json", "https://evil.example"); status != http.StatusForbidden {
+		t.Errorf("other origin: status = %d, want 403", status)
+	}
+	if status := post("application/json", ts.URL); status == http.StatusForbidden {
+		t.Errorf("same origin: status = %d, want it served", status)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	ctrl, ts := newTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/events?token=secret"
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.CloseNow()
+
+	if !ctrl.WaitSubscribers(1, 2*time.Second) {
+		t.Fatal("the event stream did not subscribe")
+	}
+	ctrl.Publish(player.PlayerProgressMsg{Current: "01:00", Duration: "04:00", Progress: 25, Position: 60, Length: 240})
+
+	var e player.Event
+	if err := wsjson.Read(ctx, conn, &e); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	msg, err := player.DecodeEvent(e)
+	if err != nil {
+		t.Fatalf("DecodeEvent() error = %v", err)
+	}
+	if progress, ok := msg.(player.PlayerProgressMsg); !ok || progress.Progress != 25 {
+		t.Errorf("event = %#v, want progress 25", msg)
+	}
+}
+
+func TestRemotePage(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp := do(t, "GET", ts.URL+"/", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200 without token", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+}
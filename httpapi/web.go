@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"embed"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+func webHandler() http.Handler {
+	root, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(root)
+}
+
+// RemoteURL returns the address a phone on the same network should open,
+// or "" when the server only listens on loopback.
+func (s *Server) RemoteURL() string {
+	addr, ok := s.ln.Addr().(*net.TCPAddr)
+	if !ok || addr.IP.IsLoopback() {
+		return ""
+	}
+	host := addr.IP
+	if host.IsUnspecified() {
+		host = lanIP()
+		if host == nil {
+			return ""
+		}
+	}
+	u := url.URL{Scheme: "http", Host: net.JoinHostPort(host.String(), strconv.Itoa(addr.Port)), Path: "/"}
+	if s.token != "" {
+		u.RawQuery = url.Values{"token": {s.token}}.Encode()
+	}
+	return u.String()
+}
+
+// lanIP picks the first private IPv4 address of the machine.
+func lanIP() net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil && ipnet.IP.IsPrivate() {
+			return ipnet.IP
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"player/loudness"
+	"player/paths"
+	"player/player"
+)
+
+// Record is everything the library remembers about one video.
+type Record struct {
+	Video      player.VideoInfo `json:"video"`
+	PlayCount  int              `json:"play_count"`
+	LastPlayed time.Time        `json:"last_played"`
+	// Loudness is measured on the first play of a stream.
+	Loudness *loudness.Measurement `json:"loudness,omitempty"`
+	// Speed is the playback rate chosen while the video played; the
+	// latest one is reused for every video of the same uploader.
+	Speed float64 `json:"speed,omitempty"`
+	// Position is where a long track was left in seconds, 0 to play it
+	// from the start. Length is the track length it was left at.
+	Position float64 `json:"position,omitempty"`
+	Length   float64 `json:"length,omitempty"`
+	// Completed is set once Completion of the track was played.
+	Completed bool `json:"completed,omitempty"`
+}
+
+// Completion is the share of a track from which it counts as heard.
+const Completion = 0.95
+
+// Heard is the share of the track played, 1 once completed and 0 when
+// nothing was kept.
+func (r Record) Heard() float64 {
+	switch {
+	case r.Position > 0 && r.Length > 0:
+		return min(r.Position/r.Length, 1)
+	case r.Completed:
+		return 1
+	}
+	return 0
+}
+
+type Library struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]*Record
+}
+
+func DefaultPath() string {
+	return filepath.Join(paths.DataDir(), "library.json")
+}
+
+// Open loads the library stored at path. A missing file yields an empty library.
+func Open(path string) (*Library, error) {
+	l := &Library{
+		path:    path,
+		records: make(map[string]*Record),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &l.records); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Library) Get(id string) (Record, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.records[id]
+	if !ok {
+		return Record{}, false
+	}
+	return *r, true
+}
+
+// Update applies fn to the record of video, creating it if needed, and saves.
+func (l *Library) Update(video player.VideoInfo, fn func(*Record)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.records[video.ID]
+	if !ok {
+		r = &Record{}
+		l.records[video.ID] = r
+	}
+	// The queue does not carry the chapters found on a previous play.
+	if len(video.Chapters) == 0 {
+		video.Chapters = r.Video.Chapters
+	}
+	r.Video = video
+	fn(r)
+	return l.save()
+}
+
+func (l *Library) RecordPlay(video player.VideoInfo) error {
+	return l.Update(video, func(r *Record) {
+		r.PlayCount++
+		r.LastPlayed = time.Now()
+	})
+}
+
+// SavePosition remembers where the video id was left, or marks it
+// completed once Completion of it was played. Unknown videos are ignored.
+func (l *Library) SavePosition(id string, position, length float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.records[id]
+	if !ok || length <= 0 {
+		return nil
+	}
+	r.Position, r.Length = position, length
+	if position >= length*Completion {
+		r.Position = 0
+		r.Completed = true
+	}
+	return l.save()
+}
+
+// Speed returns the playback rate last chosen for a video of uploader.
+func (l *Library) Speed(uploader string) (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var latest *Record
+	for _, r := range l.records {
+		if r.Speed == 0 || r.Video.Uploader != uploader {
+			continue
+		}
+		if latest == nil || r.LastPlayed.After(latest.LastPlayed) {
+			latest = r
+		}
+	}
+	if latest == nil {
+		return 0, false
+	}
+	return latest.Speed, true
+}
+
+// Records returns every record, most recently played first.
+func (l *Library) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records := make([]Record, 0, len(l.records))
+	for _, r := range l.records {
+		records = append(records, *r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LastPlayed.After(records[j].LastPlayed)
+	})
+	return records
+}
+
+func (l *Library) save() error {
+	data, err := json.MarshalIndent(l.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
@@ -0,0 +1,107 @@
+// Package logging sends the log of a process to a rotating file in the
+// state directory and to the subscribers of its records, such as the log
+// pane of the TUI.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"player/paths"
+	"player/player"
+)
+
+// bus carries every record as a player.LogMsg, whatever the level of the
+// file.
+var bus = player.NewBus()
+
+// Path is the log file of the process called name.
+func Path(name string) string {
+	return filepath.Join(paths.StateDir(), name+".log")
+}
+
+// Setup makes the default slog logger, and the log package through it,
+// write to Path(name). Debug records reach the file only when debug is set.
+// The returned closer flushes the file on exit.
+func Setup(name string, debug bool) (io.Closer, error) {
+	file, err := openRotating(Path(name))
+	if err != nil {
+		return nil, err
+	}
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(&handler{
+		file: slog.NewTextHandler(file, &slog.HandlerOptions{Level: level}),
+	}))
+	return file, nil
+}
+
+// Subscribe returns the records logged from now on, as player.LogMsg.
+func Subscribe() (<-chan player.PlayerMsg, func()) {
+	return bus.Subscribe()
+}
+
+// handler writes records to the file and publishes them. The "component"
+// attribute names the part of the program a record comes from.
+type handler struct {
+	file      slog.Handler
+	component string
+	attrs     string
+}
+
+func (h *handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	msg := player.LogMsg{Time: r.Time, Level: r.Level, Component: h.component}
+	text := r.Message + h.attrs
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "component" {
+			msg.Component = a.Value.String()
+		} else {
+			text += formatAttr(a)
+		}
+		return true
+	})
+	msg.Message = text
+	bus.Publish(msg)
+
+	if !h.file.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.file.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.file = h.file.WithAttrs(attrs)
+	for _, a := range attrs {
+		if a.Key == "component" {
+			c.component = a.Value.String()
+		} else {
+			c.attrs += formatAttr(a)
+		}
+	}
+	return &c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.file = h.file.WithGroup(name)
+	return &c
+}
+
+func formatAttr(a slog.Attr) string {
+	value := a.Value.Resolve().String()
+	if value == "" || strings.ContainsAny(value, " \"=") {
+		value = strconv.Quote(value)
+	}
+	return " " + a.Key + "=" + value
+}
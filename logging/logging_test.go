@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"player/player"
+)
+
+func TestRecordsArePublishedAtEveryLevel(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	defer slog.SetDefault(slog.Default())
+	file, err := Setup("test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	slog.With("component", "resolver").Debug("resolving", "video", "abc", "detail", "two words")
+	select {
+	case msg := <-records:
+		want := player.LogMsg{Level: slog.LevelDebug, Component: "resolver", Message: `resolving video=abc detail="two words"`}
+		got := msg.(player.LogMsg)
+		got.Time = time.Time{}
+		if got != want {
+			t.Errorf("published %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("record not published")
+	}
+	slog.Info("daemon started")
+	file.Close()
+
+	data, err := os.ReadFile(Path("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "resolving") {
+		t.Error("debug record written without debug")
+	}
+	if !strings.Contains(string(data), "daemon started") {
+		t.Errorf("log file = %q, want the info record", data)
+	}
+}
+
+func TestFileIsRotated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	f, err := openRotating(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for range (keep + 2) * maxSize / len(line) {
+		if _, err := f.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 1; i <= keep; i++ {
+		info, err := os.Stat(fmt.Sprintf("%s.%d", path, i))
+		if err != nil {
+			t.Fatalf("rotated file %d: %v", i, err)
+		}
+		if info.Size() > maxSize {
+			t.Errorf("rotated file %d is %d bytes, over %d", i, info.Size(), maxSize)
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", path, keep+1)); err == nil {
+		t.Error("more rotated files kept than allowed")
+	}
+}
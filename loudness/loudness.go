@@ -0,0 +1,116 @@
+package loudness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Mode selects how tracks are levelled.
+type Mode string
+
+const (
+	Off Mode = "off"
+	// Track and Album use the ReplayGain tags of local files; streams get
+	// a gain from their measured R128 loudness either way.
+	Track Mode = "track"
+	Album Mode = "album"
+	// Dynamic runs dynaudnorm on everything.
+	Dynamic Mode = "dynamic"
+)
+
+var Modes = []Mode{Off, Track, Album, Dynamic}
+
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if strings.EqualFold(s, string(m)) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown normalization mode %q", s)
+}
+
+// Next cycles through Modes.
+func (m Mode) Next() Mode {
+	for i, mode := range Modes {
+		if mode == m {
+			return Modes[(i+1)%len(Modes)]
+		}
+	}
+	return Off
+}
+
+const (
+	// Target is the ReplayGain 2.0 reference level, in LUFS.
+	Target = -18.0
+	// MaxPeak keeps a boosted track from clipping, in dBTP.
+	MaxPeak = -1.0
+)
+
+// LiveFilter levels a stream that has not been measured yet.
+const LiveFilter = "lavfi=[dynaudnorm]"
+
+// Measurement is the EBU R128 loudness of a whole track.
+type Measurement struct {
+	Integrated float64 `json:"integrated"`
+	TruePeak   float64 `json:"true_peak"`
+}
+
+// Gain brings the track to Target without pushing its peak over MaxPeak.
+func (m Measurement) Gain() float64 {
+	return min(Target-m.Integrated, MaxPeak-m.TruePeak)
+}
+
+// Filter is the mpv audio filter applying the gain.
+func (m Measurement) Filter() string {
+	return fmt.Sprintf("lavfi=[volume=%.2fdB]", m.Gain())
+}
+
+// Analyze decodes at most the first seconds of input with ffmpeg's loudnorm
+// filter in measurement mode.
+func Analyze(ctx context.Context, input string, seconds float64) (Measurement, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-nostdin", "-hide_banner", "-nostats",
+		"-t", strconv.FormatFloat(seconds, 'f', -1, 64),
+		"-i", input,
+		"-vn", "-af", "loudnorm=print_format=json",
+		"-f", "null", "-",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Measurement{}, fmt.Errorf("ffmpeg: %w", err)
+	}
+	return parseLoudnorm(stderr.Bytes())
+}
+
+// parseLoudnorm reads the JSON summary loudnorm prints last.
+func parseLoudnorm(out []byte) (Measurement, error) {
+	start := bytes.LastIndexByte(out, '{')
+	end := bytes.LastIndexByte(out, '}')
+	if start < 0 || end < start {
+		return Measurement{}, errors.New("loudnorm printed no summary")
+	}
+	var summary struct {
+		InputI  string `json:"input_i"`
+		InputTP string `json:"input_tp"`
+	}
+	if err := json.Unmarshal(out[start:end+1], &summary); err != nil {
+		return Measurement{}, err
+	}
+	integrated, err := strconv.ParseFloat(summary.InputI, 64)
+	if err != nil || math.IsInf(integrated, 0) {
+		return Measurement{}, fmt.Errorf("no loudness measured (%q)", summary.InputI)
+	}
+	peak, err := strconv.ParseFloat(summary.InputTP, 64)
+	if err != nil || math.IsInf(peak, 0) {
+		peak = MaxPeak
+	}
+	return Measurement{Integrated: integrated, TruePeak: peak}, nil
+}
@@ -0,0 +1,57 @@
+package loudness
+
+import "testing"
+
+const loudnormOutput = `Input #0, matroska,webm, from 'stream':
+  Duration: 00:03:32.43, start: -0.007000, bitrate: 133 kb/s
+[Parsed_loudnorm_0 @ 0x5581c1a2b6c0]
+{
+	"input_i" : "-9.52",
+	"input_tp" : "0.41",
+	"input_lra" : "5.20",
+	"input_thresh" : "-19.66",
+	"output_i" : "-23.70",
+	"output_tp" : "-3.82",
+	"output_lra" : "4.30",
+	"output_thresh" : "-33.84",
+	"normalization_type" : "dynamic",
+	"target_offset" : "-0.30"
+}
+`
+
+func TestParseLoudnorm(t *testing.T) {
+	m, err := parseLoudnorm([]byte(loudnormOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Integrated != -9.52 || m.TruePeak != 0.41 {
+		t.Errorf("got %+v", m)
+	}
+
+	if _, err := parseLoudnorm([]byte(`{"input_i" : "-inf", "input_tp" : "-inf"}`)); err == nil {
+		t.Error("silence should not give a measurement")
+	}
+}
+
+func TestGain(t *testing.T) {
+	// A loud master is turned down to the target.
+	if g := (Measurement{Integrated: -9.5, TruePeak: 0.4}).Gain(); g != -8.5 {
+		t.Errorf("loud track gain = %v, want -8.5", g)
+	}
+	// A quiet one is only raised until its peak reaches MaxPeak.
+	if g := (Measurement{Integrated: -25, TruePeak: -4}).Gain(); g != 3 {
+		t.Errorf("quiet track gain = %v, want 3", g)
+	}
+}
+
+func TestModes(t *testing.T) {
+	if m, err := ParseMode("Album"); err != nil || m != Album {
+		t.Errorf("ParseMode(Album) = %q, %v", m, err)
+	}
+	if _, err := ParseMode("loud"); err == nil {
+		t.Error("ParseMode(loud) should fail")
+	}
+	if Dynamic.Next() != Off {
+		t.Error("Next should wrap around")
+	}
+}
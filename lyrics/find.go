@@ -0,0 +1,148 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"player/paths"
+	"player/player"
+)
+
+var ErrNotFound = errors.New("no lyrics found")
+
+// DefaultSubLangs asks yt-dlp for English or French subtitles, manual or
+// automatic.
+const DefaultSubLangs = "en.*,fr.*"
+
+// Finder looks for lyrics next to local files, in a lyrics directory and
+// finally in the YouTube subtitles.
+type Finder struct {
+	Dir      string
+	CacheDir string
+	SubLangs string
+}
+
+func DefaultDir() string {
+	return filepath.Join(paths.DataDir(), "lyrics")
+}
+
+func NewFinder(dir, subLangs string) Finder {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	if subLangs == "" {
+		subLangs = DefaultSubLangs
+	}
+	return Finder{
+		Dir:      dir,
+		CacheDir: filepath.Join(paths.CacheDir(), "subs"),
+		SubLangs: subLangs,
+	}
+}
+
+func (f Finder) candidates(video player.VideoInfo) []string {
+	var files []string
+	if video.IsLocal() {
+		files = append(files, strings.TrimSuffix(video.Path, filepath.Ext(video.Path))+".lrc")
+	} else {
+		files = append(files, filepath.Join(f.Dir, video.ID+".lrc"))
+	}
+	return append(files, filepath.Join(f.Dir, safeName(video.Title)+".lrc"))
+}
+
+func (f Finder) Find(ctx context.Context, video player.VideoInfo) (*Lyrics, error) {
+	for _, file := range f.candidates(video) {
+		l, err := readLRC(file)
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if video.IsLocal() {
+		return nil, ErrNotFound
+	}
+	return f.subtitles(ctx, video)
+}
+
+func readLRC(file string) (*Lyrics, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	l, err := ParseLRC(fd)
+	if err != nil {
+		return nil, err
+	}
+	l.Path = file
+	return l, nil
+}
+
+// subtitles downloads the video's subtitles, or its automatic captions,
+// with yt-dlp.
+func (f Finder) subtitles(ctx context.Context, video player.VideoInfo) (*Lyrics, error) {
+	if err := os.MkdirAll(f.CacheDir, 0o700); err != nil {
+		return nil, err
+	}
+	pattern := filepath.Join(f.CacheDir, video.ID+".*.vtt")
+	files, _ := filepath.Glob(pattern)
+	if len(files) == 0 {
+		_, err := player.Ytdlp().
+			SkipDownload().
+			WriteSubs().
+			WriteAutoSubs().
+			SubFormat("vtt").
+			SubLangs(f.SubLangs).
+			Output(filepath.Join(f.CacheDir, "%(id)s.%(ext)s")).
+			NoWarnings().
+			Run(ctx, player.WatchURL(video.ID))
+		if err != nil {
+			return nil, fmt.Errorf("fetching subtitles: %w", err)
+		}
+		files, _ = filepath.Glob(pattern)
+	}
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+
+	fd, err := os.Open(files[0])
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	l, err := ParseVTT(fd)
+	if err != nil {
+		return nil, err
+	}
+	if len(l.Lines) == 0 {
+		return nil, ErrNotFound
+	}
+	return l, nil
+}
+
+// Save writes the lyrics, with their current offset, back to their LRC
+// file. Lyrics that came from subtitles are saved in the lyrics directory.
+func (f Finder) Save(video player.VideoInfo, l *Lyrics) error {
+	if l.Path == "" {
+		if err := os.MkdirAll(f.Dir, 0o700); err != nil {
+			return err
+		}
+		l.Path = f.candidates(video)[0]
+	}
+	return os.WriteFile(l.Path, []byte(l.LRC()), 0o644)
+}
+
+func safeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
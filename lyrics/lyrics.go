@@ -0,0 +1,102 @@
+package lyrics
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Line struct {
+	Time time.Duration
+	Text string
+}
+
+// Lyrics are timed lines. Offset shifts every line, positive values show
+// them earlier, as in the LRC [offset:] tag.
+type Lyrics struct {
+	Lines  []Line
+	Offset time.Duration
+	// Path is the LRC file the lyrics were read from, if any.
+	Path string
+}
+
+var (
+	timeTagRegex   = regexp.MustCompile(`\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	offsetTagRegex = regexp.MustCompile(`(?i)^\[offset:\s*([+-]?\d+)\s*\]`)
+)
+
+// ParseLRC reads LRC lyrics. A line may carry several time tags; metadata
+// tags other than offset are ignored.
+func ParseLRC(r io.Reader) (*Lyrics, error) {
+	l := &Lyrics{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := offsetTagRegex.FindStringSubmatch(line); m != nil {
+			ms, _ := strconv.Atoi(m[1])
+			l.Offset = time.Duration(ms) * time.Millisecond
+			continue
+		}
+		var times []time.Duration
+		for {
+			loc := timeTagRegex.FindStringSubmatchIndex(line)
+			if loc == nil || loc[0] != 0 {
+				break
+			}
+			m := timeTagRegex.FindStringSubmatch(line)
+			times = append(times, parseTimeTag(m[1], m[2], m[3]))
+			line = line[loc[1]:]
+		}
+		text := strings.TrimSpace(line)
+		for _, t := range times {
+			l.Lines = append(l.Lines, Line{Time: t, Text: text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(l.Lines, func(i, j int) bool { return l.Lines[i].Time < l.Lines[j].Time })
+	return l, nil
+}
+
+func parseTimeTag(min, sec, frac string) time.Duration {
+	m, _ := strconv.Atoi(min)
+	s, _ := strconv.Atoi(sec)
+	d := time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if frac != "" {
+		// "5" is 500ms, "05" is 50ms, "005" is 5ms.
+		f, _ := strconv.Atoi((frac + "00")[:3])
+		d += time.Duration(f) * time.Millisecond
+	}
+	return d
+}
+
+// Index returns the line being sung at pos, or -1 before the first line.
+func (l *Lyrics) Index(pos time.Duration) int {
+	pos += l.Offset
+	return sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].Time > pos }) - 1
+}
+
+// LRC formats the lyrics back to LRC, including the offset.
+func (l *Lyrics) LRC() string {
+	var b strings.Builder
+	if l.Offset != 0 {
+		b.WriteString("[offset:" + strconv.FormatInt(l.Offset.Milliseconds(), 10) + "]\n")
+	}
+	for _, line := range l.Lines {
+		ms := line.Time.Milliseconds()
+		b.WriteString("[" + pad(ms/60000) + ":" + pad(ms/1000%60) + "." + pad(ms%1000/10) + "]" + line.Text + "\n")
+	}
+	return b.String()
+}
+
+func pad(n int64) string {
+	if n < 10 {
+		return "0" + strconv.FormatInt(n, 10)
+	}
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,90 @@
+package lyrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLRC(t *testing.T) {
+	const lrc = `[ti:Song]
+[offset:+500]
+[00:12.00]First line
+[00:15.5][01:02.25]Chorus
+[00:20.123]Third
+`
+	l, err := ParseLRC(strings.NewReader(lrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Offset != 500*time.Millisecond {
+		t.Errorf("Offset = %v, want 500ms", l.Offset)
+	}
+	want := []Line{
+		{12 * time.Second, "First line"},
+		{15500 * time.Millisecond, "Chorus"},
+		{20123 * time.Millisecond, "Third"},
+		{62250 * time.Millisecond, "Chorus"},
+	}
+	if len(l.Lines) != len(want) {
+		t.Fatalf("Lines = %v, want %v", l.Lines, want)
+	}
+	for i := range want {
+		if l.Lines[i] != want[i] {
+			t.Errorf("Lines[%d] = %v, want %v", i, l.Lines[i], want[i])
+		}
+	}
+
+	for _, tt := range []struct {
+		pos  time.Duration
+		want int
+	}{
+		{0, -1},
+		{11600 * time.Millisecond, 0},
+		{15 * time.Second, 1},
+		{2 * time.Minute, 3},
+	} {
+		if got := l.Index(tt.pos); got != tt.want {
+			t.Errorf("Index(%v) = %d, want %d", tt.pos, got, tt.want)
+		}
+	}
+
+	again, err := ParseLRC(strings.NewReader(l.LRC()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Offset != l.Offset || len(again.Lines) != len(l.Lines) {
+		t.Errorf("LRC() round trip = %+v, want %+v", again, l)
+	}
+}
+
+func TestParseVTTRollingCaptions(t *testing.T) {
+	const vtt = `WEBVTT
+Kind: captions
+
+00:00:01.000 --> 00:00:03.000 align:start position:0%
+hello<00:00:01.500><c> world</c>
+
+00:00:03.000 --> 00:00:05.000 align:start position:0%
+hello world
+how are you
+
+00:00:05.000 --> 00:00:07.000
+how are you
+hello world
+`
+	l, err := ParseVTT(strings.NewReader(vtt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range l.Lines {
+		got = append(got, line.Text)
+	}
+	if strings.Join(got, "|") != "hello world|how are you" {
+		t.Errorf("lines = %q", got)
+	}
+	if l.Lines[1].Time != 3*time.Second {
+		t.Errorf("second line at %v, want 3s", l.Lines[1].Time)
+	}
+}
@@ -0,0 +1,64 @@
+package lyrics
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	cueTimeRegex = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})\s+-->`)
+	vttTagRegex  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseVTT reads WebVTT subtitles as lyrics. YouTube auto-captions repeat
+// the previous line at the top of each cue, so lines already shown by the
+// previous cues are dropped.
+func ParseVTT(r io.Reader) (*Lyrics, error) {
+	l := &Lyrics{}
+	scanner := bufio.NewScanner(r)
+	var (
+		start time.Duration
+		inCue bool
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := cueTimeRegex.FindStringSubmatch(line); m != nil {
+			start = parseTimeTag(m[2], m[3], m[4]) + hours(m[1])
+			inCue = true
+			continue
+		}
+		if line == "" {
+			inCue = false
+			continue
+		}
+		if !inCue {
+			continue
+		}
+		text := strings.TrimSpace(vttTagRegex.ReplaceAllString(line, ""))
+		if text == "" || l.recent(text) {
+			continue
+		}
+		l.Lines = append(l.Lines, Line{Time: start, Text: text})
+	}
+	return l, scanner.Err()
+}
+
+func hours(h string) time.Duration {
+	n, _ := strconv.Atoi(h)
+	return time.Duration(n) * time.Hour
+}
+
+// recent reports whether text is one of the last two lines, which is what
+// a rolling caption window repeats.
+func (l *Lyrics) recent(text string) bool {
+	for i := len(l.Lines) - 1; i >= 0 && i >= len(l.Lines)-2; i-- {
+		if l.Lines[i].Text == text {
+			return true
+		}
+	}
+	return false
+}
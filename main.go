@@ -1,20 +1,130 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"slices"
 
+	"player/config"
+	"player/daemon"
+	"player/doctor"
+	"player/library"
+	"player/logging"
+	"player/player"
 	"player/tui"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	//ytdlp.MustInstall(context.TODO(), nil)
-	m := tui.NewModel()
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fs := flag.NewFlagSet("ghost_player", flag.ExitOnError)
+	socket := fs.String("socket", daemon.DefaultSocketPath(), "daemon control socket")
+	standalone := fs.Bool("standalone", false, "play inside the TUI process instead of the daemon")
+	device := fs.String("device", "", "switch the audio output, of the daemon for every client unless standalone; see the devices command")
+	debug := fs.Bool("debug", os.Getenv(debugEnv) != "", "log debug records, also in the daemon started (default $"+debugEnv+")")
+	fs.Usage = usage(fs)
+	fs.Parse(os.Args[1:])
+
+	if *debug {
+		// A daemon started by Connect inherits the setting.
+		os.Setenv(debugEnv, "1")
+	}
+	logFile, err := logging.Setup("tui", *debug)
+	if err != nil {
+		fatal(err)
+	}
+	defer logFile.Close()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal(err)
+	}
+	checkDependencies(cfg)
+
+	var ctrl daemon.Controller
+	if *standalone {
+		lib, err := library.Open(library.DefaultPath())
+		if err != nil {
+			fatal(err)
+		}
+		if _, err := player.CleanOrphans(); err != nil {
+			log.Print(err)
+		}
+		p := player.NewPlayer()
+		defer p.Close()
+		svc := daemon.NewService(p, lib)
+		svc.Configure(cfg)
+		svc.ForwardLogs()
+		defer daemon.RestoreSession(svc, cfg)()
+		ctrl = svc
+	} else {
+		client, err := daemon.Connect(*socket)
+		if err != nil {
+			fatal(err)
+		}
+		defer client.Close()
+		client.ForwardLogs()
+		ctrl = client
+	}
+
+	if *device != "" {
+		if err := ctrl.SetAudioDevice(*device); err != nil {
+			fatal(err)
+		}
+	}
+
+	m := tui.NewModel(ctrl, cfg)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
-	_, err := p.Run()
+	final, err := p.Run()
+	// The daemon counts the clients showing the visualizer.
+	if m, ok := final.(tui.Model); ok && m.VisualizerShown() {
+		_ = ctrl.SetVisualizer(false)
+	}
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
+	}
+}
+
+// debugEnv turns debug logging on, in the TUI and in the daemon.
+const debugEnv = "GHOST_PLAYER_DEBUG"
+
+// fatal reports err on the terminal, since the log goes to a file.
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// checkDependencies stops before the TUI opens if mpv or yt-dlp cannot be
+// used, installing yt-dlp first when the config lets the app manage it. An
+// outdated yt-dlp is only reported.
+func checkDependencies(cfg config.Config) {
+	ctx := context.Background()
+	checks := doctor.Run(ctx)
+	isYtdlp := func(c doctor.Check) bool { return c.Name == "yt-dlp" }
+	if cfg.Ytdlp.Managed && (slices.ContainsFunc(doctor.Missing(checks), isYtdlp) || slices.ContainsFunc(doctor.Warned(checks), isYtdlp)) {
+		fmt.Fprintf(os.Stderr, "Installing yt-dlp %s...\n", player.YtdlpVersion)
+		if _, err := player.InstallYtdlp(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		checks = doctor.Run(ctx)
+	}
+	if missing := doctor.Missing(checks); len(missing) > 0 {
+		doctor.Report(os.Stderr, missing)
+		fatal(fmt.Errorf("run `%s doctor` for details", os.Args[0]))
 	}
+	doctor.Report(os.Stderr, doctor.Warned(checks))
 }
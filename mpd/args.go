@@ -0,0 +1,58 @@
+package mpd
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// splitArgs tokenizes an MPD command line. Arguments may be wrapped in
+// double quotes, inside which backslash escapes the next character.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args []string
+		cur  strings.Builder
+		in   bool
+		have bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case in && c == '\\':
+			i++
+			if i == len(line) {
+				return nil, errors.New("unterminated escape")
+			}
+			cur.WriteByte(line[i])
+		case c == '"':
+			in = !in
+			have = true
+		case !in && (c == ' ' || c == '\t'):
+			if have {
+				args = append(args, cur.String())
+				cur.Reset()
+				have = false
+			}
+		default:
+			cur.WriteByte(c)
+			have = true
+		}
+	}
+	if in {
+		return nil, errors.New("missing closing quote")
+	}
+	if have {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+func sortedCommands() []string {
+	names := make([]string, 0, len(commandTable)+5)
+	for name := range commandTable {
+		names = append(names, name)
+	}
+	names = append(names, "idle", "close", "command_list_begin", "command_list_ok_begin", "command_list_end")
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,437 @@
+package mpd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"player/player"
+)
+
+// MPD error codes, see the protocol documentation.
+const (
+	ackArg      = 2
+	ackUnknown  = 5
+	ackNoExist  = 50
+	ackSystem   = 52
+	ackPlayer   = 53
+	searchLimit = 20
+)
+
+type ackError struct {
+	code    int
+	command string
+	message string
+}
+
+func (e *ackError) Error() string {
+	return e.message
+}
+
+type commandFunc func(ss *session, args []string) error
+
+var commandTable map[string]commandFunc
+
+func init() {
+	commandTable = map[string]commandFunc{
+		"ping":         func(*session, []string) error { return nil },
+		"status":       (*session).status,
+		"currentsong":  (*session).currentSong,
+		"play":         (*session).play,
+		"playid":       (*session).playID,
+		"pause":        (*session).pause,
+		"stop":         func(ss *session, _ []string) error { return ss.s.ctrl.Stop() },
+		"next":         func(ss *session, _ []string) error { return ss.s.ctrl.Next() },
+		"previous":     func(ss *session, _ []string) error { return ss.s.ctrl.Previous() },
+		"seek":         (*session).seek,
+		"seekid":       (*session).seek,
+		"seekcur":      (*session).seekCur,
+		"setvol":       (*session).setVol,
+		"random":       (*session).random,
+		"repeat":       (*session).repeat,
+		"playlistinfo": (*session).playlistInfo,
+		"playlistid":   (*session).playlistInfo,
+		"add":          (*session).add,
+		"addid":        (*session).addID,
+		"delete":       (*session).delete,
+		"deleteid":     (*session).deleteID,
+		"search":       (*session).search,
+		"find":         (*session).search,
+		"noidle":       func(*session, []string) error { return nil },
+		"commands":     (*session).commands,
+		"tagtypes":     func(ss *session, _ []string) error { ss.printf("tagtype: Artist\ntagtype: Title\n"); return nil },
+		"outputs": func(ss *session, _ []string) error {
+			ss.printf("outputid: 0\noutputname: mpv\noutputenabled: 1\n")
+			return nil
+		},
+		"stats":         func(*session, []string) error { return nil },
+		"listplaylists": func(*session, []string) error { return nil },
+	}
+}
+
+func (ss *session) exec(line string) error {
+	args, err := splitArgs(line)
+	if err != nil {
+		return &ackError{code: ackArg, message: err.Error()}
+	}
+	if len(args) == 0 {
+		return &ackError{code: ackUnknown, message: "No command given"}
+	}
+	fn, ok := commandTable[args[0]]
+	if !ok {
+		return &ackError{code: ackUnknown, message: fmt.Sprintf("unknown command %q", args[0])}
+	}
+	if err := fn(ss, args[1:]); err != nil {
+		if ackErr, ok := err.(*ackError); ok {
+			ackErr.command = args[0]
+			return ackErr
+		}
+		return &ackError{code: ackSystem, command: args[0], message: err.Error()}
+	}
+	return nil
+}
+
+func (ss *session) printf(format string, a ...any) {
+	fmt.Fprintf(ss.w, format, a...)
+}
+
+func intArg(args []string, i int) (int, error) {
+	if i >= len(args) {
+		return 0, &ackError{code: ackArg, message: "missing argument"}
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil {
+		return 0, &ackError{code: ackArg, message: fmt.Sprintf("Integer expected: %s", args[i])}
+	}
+	return n, nil
+}
+
+func floatArg(args []string, i int) (float64, error) {
+	if i >= len(args) {
+		return 0, &ackError{code: ackArg, message: "missing argument"}
+	}
+	f, err := strconv.ParseFloat(args[i], 64)
+	if err != nil {
+		return 0, &ackError{code: ackArg, message: fmt.Sprintf("Number expected: %s", args[i])}
+	}
+	return f, nil
+}
+
+// boolArg reads the 0 or 1 at args[i].
+func boolArg(args []string, i int) (bool, error) {
+	n, err := intArg(args, i)
+	if err != nil {
+		return false, err
+	}
+	if n != 0 && n != 1 {
+		return false, &ackError{code: ackArg, message: fmt.Sprintf("Boolean (0/1) expected: %s", args[i])}
+	}
+	return n == 1, nil
+}
+
+// Songs are identified by their queue position plus one.
+func songID(pos int) int { return pos + 1 }
+
+func (ss *session) printSong(video player.VideoInfo, pos int) {
+	ss.printf("file: %s\n", video.Location())
+	if video.Uploader != "" {
+		ss.printf("Artist: %s\n", video.Uploader)
+	}
+	ss.printf("Title: %s\n", video.Title)
+	if video.Duration > 0 {
+		ss.printf("Time: %d\nduration: %.3f\n", int(video.Duration), video.Duration)
+	}
+	if pos >= 0 {
+		ss.printf("Pos: %d\nId: %d\n", pos, songID(pos))
+	}
+}
+
+func mpdState(state string) string {
+	switch state {
+	case player.StateName(player.Playing), player.StateName(player.Loading):
+		return "play"
+	case player.StateName(player.Paused):
+		return "pause"
+	}
+	return "stop"
+}
+
+func (ss *session) status(_ []string) error {
+	status, err := ss.s.ctrl.Status()
+	if err != nil {
+		return err
+	}
+	queue, err := ss.s.ctrl.Queue()
+	if err != nil {
+		return err
+	}
+	ss.printf("volume: %d\nrepeat: %d\nrandom: %d\nsingle: 0\nconsume: 0\n", status.Volume, flag(status.Repeat), flag(status.Shuffle))
+	ss.printf("playlist: %d\nplaylistlength: %d\n", ss.s.playlistVersion(), len(queue))
+	state := mpdState(status.State)
+	ss.printf("state: %s\n", state)
+	if status.Index >= 0 && status.Index < len(queue) {
+		ss.printf("song: %d\nsongid: %d\n", status.Index, songID(status.Index))
+		if status.Index+1 < len(queue) {
+			ss.printf("nextsong: %d\nnextsongid: %d\n", status.Index+1, songID(status.Index+1))
+		}
+	}
+	if state != "stop" {
+		info := status.Info
+		ss.printf("time: %d:%d\nelapsed: %.3f\nduration: %.3f\n",
+			int(info.Position), int(info.Length), info.Position, info.Length)
+	}
+	return nil
+}
+
+func (ss *session) currentSong(_ []string) error {
+	status, err := ss.s.ctrl.Status()
+	if err != nil {
+		return err
+	}
+	if status.Track != nil {
+		ss.printSong(*status.Track, status.Index)
+	}
+	return nil
+}
+
+func (ss *session) play(args []string) error {
+	if len(args) == 0 {
+		status, err := ss.s.ctrl.Status()
+		if err != nil {
+			return err
+		}
+		if status.State == player.StateName(player.Paused) {
+			return ss.s.ctrl.TogglePause()
+		}
+		return ss.playPos(max(status.Index, 0))
+	}
+	pos, err := intArg(args, 0)
+	if err != nil {
+		return err
+	}
+	return ss.playPos(pos)
+}
+
+func (ss *session) playID(args []string) error {
+	if len(args) == 0 {
+		return ss.play(nil)
+	}
+	id, err := intArg(args, 0)
+	if err != nil {
+		return err
+	}
+	return ss.playPos(id - 1)
+}
+
+func (ss *session) playPos(pos int) error {
+	if err := ss.s.ctrl.PlayIndex(pos); err != nil {
+		return &ackError{code: ackArg, message: "Bad song index"}
+	}
+	return nil
+}
+
+func (ss *session) pause(args []string) error {
+	status, err := ss.s.ctrl.Status()
+	if err != nil {
+		return err
+	}
+	paused := status.State == player.StateName(player.Paused)
+	want := !paused
+	if len(args) > 0 {
+		want = args[0] == "1"
+	}
+	if want == paused || status.State == player.StateName(player.Stopped) {
+		return nil
+	}
+	return ss.s.ctrl.TogglePause()
+}
+
+func (ss *session) seek(args []string) error {
+	pos, err := intArg(args, 0)
+	if err != nil {
+		return err
+	}
+	target, err := floatArg(args, 1)
+	if err != nil {
+		return err
+	}
+	status, err := ss.s.ctrl.Status()
+	if err != nil {
+		return err
+	}
+	// "seekid" passes an id, "seek" a position; both address the current song only.
+	if pos != status.Index && pos != songID(status.Index) {
+		return &ackError{code: ackPlayer, message: "can only seek in the current song"}
+	}
+	return ss.s.ctrl.SetPosition(target)
+}
+
+func (ss *session) seekCur(args []string) error {
+	if len(args) == 0 {
+		return &ackError{code: ackArg, message: "missing argument"}
+	}
+	t, err := floatArg(args, 0)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(args[0], "+") || strings.HasPrefix(args[0], "-") {
+		return ss.s.ctrl.Seek(t)
+	}
+	return ss.s.ctrl.SetPosition(t)
+}
+
+func (ss *session) setVol(args []string) error {
+	volume, err := intArg(args, 0)
+	if err != nil {
+		return err
+	}
+	if volume < 0 || volume > 100 {
+		return &ackError{code: ackArg, message: "Invalid volume value"}
+	}
+	return ss.s.ctrl.SetVolume(volume)
+}
+
+func (ss *session) random(args []string) error {
+	enabled, err := boolArg(args, 0)
+	if err != nil {
+		return err
+	}
+	return ss.s.ctrl.SetShuffle(enabled)
+}
+
+func (ss *session) repeat(args []string) error {
+	enabled, err := boolArg(args, 0)
+	if err != nil {
+		return err
+	}
+	return ss.s.ctrl.SetRepeat(enabled)
+}
+
+// flag is how MPD writes a boolean.
+func flag(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (ss *session) playlistInfo(args []string) error {
+	queue, err := ss.s.ctrl.Queue()
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		pos, err := intArg(args, 0)
+		if err != nil {
+			return err
+		}
+		if pos < 0 || pos >= len(queue) {
+			return &ackError{code: ackArg, message: "Bad song index"}
+		}
+		ss.printSong(queue[pos], pos)
+		return nil
+	}
+	for i, video := range queue {
+		ss.printSong(video, i)
+	}
+	return nil
+}
+
+func (ss *session) add(args []string) error {
+	_, err := ss.addURI(args)
+	return err
+}
+
+func (ss *session) addID(args []string) error {
+	pos, err := ss.addURI(args)
+	if err != nil {
+		return err
+	}
+	ss.printf("Id: %d\n", songID(pos))
+	return nil
+}
+
+func (ss *session) addURI(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, &ackError{code: ackArg, message: "missing argument"}
+	}
+	video, ok := ss.s.lookup(args[0])
+	if !ok {
+		return 0, &ackError{code: ackNoExist, message: "Unsupported URI"}
+	}
+	if err := ss.s.ctrl.Enqueue(video); err != nil {
+		return 0, err
+	}
+	queue, err := ss.s.ctrl.Queue()
+	if err != nil {
+		return 0, err
+	}
+	return len(queue) - 1, nil
+}
+
+func (ss *session) delete(args []string) error {
+	pos, err := intArg(args, 0)
+	if err != nil {
+		return err
+	}
+	if err := ss.s.ctrl.Remove(pos); err != nil {
+		return &ackError{code: ackArg, message: "Bad song index"}
+	}
+	return nil
+}
+
+func (ss *session) deleteID(args []string) error {
+	id, err := intArg(args, 0)
+	if err != nil {
+		return err
+	}
+	return ss.delete([]string{strconv.Itoa(id - 1)})
+}
+
+// search accepts both the legacy "search TAG VALUE..." form and filter
+// expressions; either way the values are joined into one YouTube query.
+func (ss *session) search(args []string) error {
+	var terms []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "("):
+			terms = append(terms, filterValue(arg))
+		case arg == "sort" || arg == "window":
+			i++
+		case i+1 < len(args):
+			terms = append(terms, args[i+1])
+			i++
+		}
+	}
+	query := strings.TrimSpace(strings.Join(terms, " "))
+	if query == "" {
+		return &ackError{code: ackArg, message: "missing search terms"}
+	}
+	results, err := ss.s.Search(query, searchLimit)
+	if err != nil {
+		return err
+	}
+	ss.s.remember(results)
+	for _, video := range results {
+		ss.printSong(video, -1)
+	}
+	return nil
+}
+
+// filterValue extracts the quoted value of a filter such as
+// (Title contains 'foo').
+func filterValue(expr string) string {
+	expr = strings.Trim(expr, "() ")
+	if i := strings.IndexAny(expr, `'"`); i >= 0 {
+		return strings.Trim(expr[i:], `'"`)
+	}
+	return expr
+}
+
+func (ss *session) commands(_ []string) error {
+	for _, name := range sortedCommands() {
+		ss.printf("command: %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package mpd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+
+	"player/daemon"
+	"player/player"
+)
+
+// protocolVersion is the MPD protocol version announced to clients.
+const protocolVersion = "0.23.5"
+
+// Server implements a subset of the MPD protocol on top of a
+// daemon.Controller so that MPD clients can drive the player.
+type Server struct {
+	ctrl     daemon.Controller
+	listener net.Listener
+
+	// Search looks tracks up for the "search" and "find" commands.
+	Search func(query string, maxResults int) ([]player.VideoInfo, error)
+
+	unsubscribe func()
+
+	mu      sync.Mutex
+	version int
+	known   map[string]player.VideoInfo
+	// conns are the open client connections, closed with the server.
+	conns    map[net.Conn]struct{}
+	closed   bool
+	sessions sync.WaitGroup
+}
+
+// Listen starts serving MPD clients on addr, e.g. "localhost:6600".
+func Listen(addr string, ctrl daemon.Controller) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("mpd: %w", err)
+	}
+	s := &Server{
+		ctrl:     ctrl,
+		listener: l,
+		Search:   player.SearchYoutube,
+		version:  1,
+		known:    make(map[string]player.VideoInfo),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	events, unsubscribe := ctrl.Subscribe()
+	s.unsubscribe = unsubscribe
+	go s.track(events)
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops listening, ends the open sessions and waits for them.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.unsubscribe()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.sessions.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.sessions.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.sessions.Done()
+			s.newSession(conn).run()
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// track bumps the playlist version whenever the queue changes, as MPD
+// clients use it to decide when to reload the playlist.
+func (s *Server) track(events <-chan player.PlayerMsg) {
+	for msg := range events {
+		if _, ok := msg.(player.QueueChangedMsg); ok {
+			s.mu.Lock()
+			s.version++
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) playlistVersion() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// remember keeps search results so that a later "add" of the same URI keeps
+// the title and artist.
+func (s *Server) remember(videos []player.VideoInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range videos {
+		s.known[v.Location()] = v
+	}
+}
+
+func (s *Server) lookup(uri string) (player.VideoInfo, bool) {
+	if video, err := player.LocalTrack(uri); err == nil {
+		return video, true
+	}
+	video, ok := player.VideoFromURL(uri)
+	if !ok {
+		return video, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if known, ok := s.known[video.URL]; ok {
+		return known, true
+	}
+	video.Title = video.URL
+	return video, true
+}
+
+// subsystemOf maps player events to the MPD idle subsystem they affect.
+func subsystemOf(msg player.PlayerMsg) string {
+	switch msg.(type) {
+	case player.PlayerStateChangedMsg, player.PlayStartedMsg, player.PlayStoppedMsg:
+		return "player"
+	case player.QueueChangedMsg:
+		return "playlist"
+	case player.VolumeChangedMsg:
+		return "mixer"
+	}
+	return ""
+}
+
+type session struct {
+	s       *Server
+	conn    net.Conn
+	w       *bufio.Writer
+	changed map[string]bool
+	idle    map[string]bool
+}
+
+func (s *Server) newSession(conn net.Conn) *session {
+	return &session{
+		s:       s,
+		conn:    conn,
+		w:       bufio.NewWriter(conn),
+		changed: make(map[string]bool),
+	}
+}
+
+func (ss *session) run() {
+	defer ss.conn.Close()
+
+	events, unsubscribe := ss.s.ctrl.Subscribe()
+	defer unsubscribe()
+
+	lines := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(ss.conn)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	fmt.Fprintf(ss.w, "OK MPD %s\n", protocolVersion)
+	ss.w.Flush()
+
+	var list *commandList
+	for {
+		select {
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			if sub := subsystemOf(msg); sub != "" {
+				ss.changed[sub] = true
+				ss.flushIdle(false)
+			}
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			switch {
+			case ss.idle != nil:
+				// Only noidle is allowed while idling.
+				if line == "noidle" {
+					ss.flushIdle(true)
+				}
+			case !ss.handleLine(line, &list):
+				return
+			}
+		}
+		ss.w.Flush()
+	}
+}
+
+type commandList struct {
+	ok       bool
+	commands []string
+}
+
+// handleLine runs one protocol line and reports whether the connection
+// should stay open.
+func (ss *session) handleLine(line string, list **commandList) bool {
+	switch {
+	case *list == nil && line == "command_list_begin":
+		*list = &commandList{}
+		return true
+	case *list == nil && line == "command_list_ok_begin":
+		*list = &commandList{ok: true}
+		return true
+	case *list != nil && line == "command_list_end":
+		l := *list
+		*list = nil
+		for i, cmd := range l.commands {
+			if err := ss.exec(cmd); err != nil {
+				ss.ack(i, err)
+				return true
+			}
+			if l.ok {
+				ss.w.WriteString("list_OK\n")
+			}
+		}
+		ss.w.WriteString("OK\n")
+		return true
+	case *list != nil:
+		(*list).commands = append((*list).commands, line)
+		return true
+	case line == "close":
+		return false
+	}
+
+	if args, err := splitArgs(line); err == nil && len(args) > 0 && args[0] == "idle" {
+		ss.idle = make(map[string]bool)
+		for _, sub := range args[1:] {
+			ss.idle[sub] = true
+		}
+		ss.flushIdle(false)
+		return true
+	}
+
+	if err := ss.exec(line); err != nil {
+		ss.ack(0, err)
+		return true
+	}
+	ss.w.WriteString("OK\n")
+	return true
+}
+
+func (ss *session) ack(index int, err error) {
+	ackErr, ok := err.(*ackError)
+	if !ok {
+		ackErr = &ackError{code: ackSystem, message: err.Error()}
+	}
+	fmt.Fprintf(ss.w, "ACK [%d@%d] {%s} %s\n", ackErr.code, index, ackErr.command, ackErr.message)
+}
+
+// flushIdle answers a pending idle command if one of the subsystems it
+// waits on changed, or unconditionally when the client sent noidle.
+func (ss *session) flushIdle(force bool) {
+	if ss.idle == nil {
+		return
+	}
+	var hit []string
+	for _, sub := range []string{"player", "playlist", "mixer", "options"} {
+		if ss.changed[sub] && (len(ss.idle) == 0 || ss.idle[sub]) {
+			hit = append(hit, sub)
+		}
+	}
+	if len(hit) == 0 && !force {
+		return
+	}
+	for _, sub := range hit {
+		fmt.Fprintf(ss.w, "changed: %s\n", sub)
+		delete(ss.changed, sub)
+	}
+	ss.w.WriteString("OK\n")
+	ss.idle = nil
+}
@@ -0,0 +1,225 @@
+package mpd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"player/daemon/daemontest"
+	"player/player"
+)
+
+// client is a minimal MPD protocol client.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+	greeting := c.line()
+	if !strings.HasPrefix(greeting, "OK MPD ") {
+		t.Fatalf("greeting = %q", greeting)
+	}
+	return c
+}
+
+func (c *client) line() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("reading response: %v", err)
+	}
+	return strings.TrimSuffix(line, "\n")
+}
+
+func (c *client) send(cmd string) {
+	c.t.Helper()
+	if _, err := fmt.Fprintf(c.conn, "%s\n", cmd); err != nil {
+		c.t.Fatalf("sending %q: %v", cmd, err)
+	}
+}
+
+// response reads key/value lines until OK, or returns the ACK line as error.
+func (c *client) response() ([][2]string, error) {
+	c.t.Helper()
+	var pairs [][2]string
+	for {
+		line := c.line()
+		switch {
+		case line == "OK":
+			return pairs, nil
+		case strings.HasPrefix(line, "ACK "):
+			return pairs, fmt.Errorf("%s", line)
+		}
+		key, value, _ := strings.Cut(line, ": ")
+		pairs = append(pairs, [2]string{key, value})
+	}
+}
+
+func (c *client) cmd(cmd string) map[string]string {
+	c.t.Helper()
+	c.send(cmd)
+	pairs, err := c.response()
+	if err != nil {
+		c.t.Fatalf("%s: %v", cmd, err)
+	}
+	m := make(map[string]string)
+	for _, p := range pairs {
+		if _, ok := m[p[0]]; !ok {
+			m[p[0]] = p[1]
+		}
+	}
+	return m
+}
+
+func (c *client) values(cmd, key string) []string {
+	c.t.Helper()
+	c.send(cmd)
+	return c.collect(key)
+}
+
+// collect reads a response and returns the values of every key line.
+func (c *client) collect(key string) []string {
+	c.t.Helper()
+	pairs, err := c.response()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var values []string
+	for _, p := range pairs {
+		if p[0] == key {
+			values = append(values, p[1])
+		}
+	}
+	return values
+}
+
+func startServer(t *testing.T) (*daemontest.Controller, *Server) {
+	t.Helper()
+	ctrl := daemontest.New()
+	s, err := Listen("127.0.0.1:0", ctrl)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	s.Search = func(query string, _ int) ([]player.VideoInfo, error) {
+		return []player.VideoInfo{{ID: "dQw4w9WgXcQ", Title: "Result for " + query, Uploader: "Rick"}}, nil
+	}
+	t.Cleanup(func() { s.Close() })
+	return ctrl, s
+}
+
+func TestPlaylistCommands(t *testing.T) {
+	_, s := startServer(t)
+	c := dial(t, s.Addr().String())
+
+	files := c.values(`search any "never gonna"`, "file")
+	if len(files) != 1 || files[0] != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Fatalf("search files = %v", files)
+	}
+
+	c.cmd(`add "` + files[0] + `"`)
+	c.cmd(`add https://youtu.be/9bZkp7q19f0`)
+	if got := c.values("playlistinfo", "Title"); len(got) != 2 || got[0] != "Result for never gonna" {
+		t.Fatalf("playlistinfo titles = %v", got)
+	}
+
+	c.cmd("delete 1")
+	if got := c.cmd("status")["playlistlength"]; got != "1" {
+		t.Errorf("playlistlength = %s, want 1", got)
+	}
+
+	c.send("delete 7")
+	if _, err := c.response(); err == nil || !strings.Contains(err.Error(), "{delete}") {
+		t.Errorf("delete 7 error = %v, want ACK for delete", err)
+	}
+
+	c.cmd("play 0")
+	status := c.cmd("status")
+	if status["state"] != "play" || status["song"] != "0" {
+		t.Errorf("status after play = %v", status)
+	}
+	if got := c.cmd("currentsong")["Artist"]; got != "Rick" {
+		t.Errorf("currentsong Artist = %q, want Rick", got)
+	}
+
+	c.cmd("setvol 42")
+	if got := c.cmd("status")["volume"]; got != "42" {
+		t.Errorf("volume = %s, want 42", got)
+	}
+
+	c.cmd("random 1")
+	c.cmd("repeat 1")
+	if status := c.cmd("status"); status["random"] != "1" || status["repeat"] != "1" {
+		t.Errorf("status after random 1, repeat 1 = %v", status)
+	}
+}
+
+func TestCommandList(t *testing.T) {
+	_, s := startServer(t)
+	c := dial(t, s.Addr().String())
+
+	c.send("command_list_ok_begin")
+	c.send("ping")
+	c.send("bogus")
+	c.send("ping")
+	c.send("command_list_end")
+	if got := c.line(); got != "list_OK" {
+		t.Fatalf("first reply = %q, want list_OK", got)
+	}
+	if got := c.line(); !strings.HasPrefix(got, "ACK [5@1] {}") {
+		t.Fatalf("second reply = %q, want ACK at index 1", got)
+	}
+	c.cmd("ping")
+}
+
+func TestIdle(t *testing.T) {
+	ctrl, s := startServer(t)
+	c := dial(t, s.Addr().String())
+
+	c.send("idle playlist")
+	// Give the session time to enter idle before changing the queue.
+	time.Sleep(50 * time.Millisecond)
+	ctrl.Enqueue(player.VideoInfo{ID: "dQw4w9WgXcQ", Title: "x"})
+	if got := c.collect("changed"); len(got) != 1 || got[0] != "playlist" {
+		t.Fatalf("idle changed = %v, want [playlist]", got)
+	}
+
+	c.send("idle")
+	c.send("noidle")
+	if got := c.line(); got != "OK" {
+		t.Fatalf("noidle reply = %q, want OK", got)
+	}
+}
+
+func TestCloseEndsSessions(t *testing.T) {
+	_, s := startServer(t)
+	c := dial(t, s.Addr().String())
+	c.send("idle")
+
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close() did not return with a client connected")
+	}
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := c.r.ReadString('\n'); err == nil {
+		t.Error("the connection is still open after Close()")
+	}
+}
@@ -0,0 +1,290 @@
+package mpris
+
+import (
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"player/daemon"
+	"player/player"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+const (
+	BusName     = "org.mpris.MediaPlayer2.ghost_player"
+	objectPath  = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	rootIface   = "org.mpris.MediaPlayer2"
+	playerIface = "org.mpris.MediaPlayer2.Player"
+	noTrack     = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+)
+
+// Server exposes a daemon.Controller as an MPRIS2 media player.
+type Server struct {
+	conn        *dbus.Conn
+	ctrl        daemon.Controller
+	props       *prop.Properties
+	unsubscribe func()
+
+	mu       sync.Mutex
+	status   daemon.Status
+	metadata map[string]dbus.Variant // last published Metadata
+}
+
+// Start connects to the session bus and claims the MPRIS bus name.
+func Start(ctrl daemon.Controller) (*Server, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("mpris: %w", err)
+	}
+	s, err := New(conn, ctrl)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New exports the MPRIS objects on conn.
+func New(conn *dbus.Conn, ctrl daemon.Controller) (*Server, error) {
+	s := &Server{conn: conn, ctrl: ctrl}
+	status, _ := ctrl.Status()
+	s.status = status
+	s.metadata = metadata(status)
+
+	root := &rootObject{s}
+	pl := &playerObject{s}
+	if err := conn.Export(root, objectPath, rootIface); err != nil {
+		return nil, err
+	}
+	if err := conn.ExportWithMap(pl, playerMethodNames, objectPath, playerIface); err != nil {
+		return nil, err
+	}
+
+	props, err := prop.Export(conn, objectPath, s.propMap())
+	if err != nil {
+		return nil, err
+	}
+	s.props = props
+
+	node := &introspect.Node{
+		Name: string(objectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{Name: rootIface, Methods: introspect.Methods(root), Properties: props.Introspection(rootIface)},
+			{
+				Name:       playerIface,
+				Methods:    playerMethods(pl),
+				Properties: props.Introspection(playerIface),
+				Signals:    []introspect.Signal{{Name: "Seeked", Args: []introspect.Arg{{Name: "Position", Type: "x"}}}},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), objectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("mpris: bus name %s already taken", BusName)
+	}
+
+	events, unsubscribe := ctrl.Subscribe()
+	s.unsubscribe = unsubscribe
+	go s.listen(events)
+	return s, nil
+}
+
+func (s *Server) Close() error {
+	s.unsubscribe()
+	_, _ = s.conn.ReleaseName(BusName)
+	return s.conn.Close()
+}
+
+func (s *Server) propMap() prop.Map {
+	ro := func(v any) *prop.Prop {
+		return &prop.Prop{Value: v, Emit: prop.EmitTrue}
+	}
+	return prop.Map{
+		rootIface: {
+			"CanQuit":             {Value: false, Emit: prop.EmitConst},
+			"CanRaise":            {Value: false, Emit: prop.EmitConst},
+			"HasTrackList":        {Value: false, Emit: prop.EmitConst},
+			"Identity":            {Value: "ghost_player", Emit: prop.EmitConst},
+			"SupportedUriSchemes": {Value: []string{"https"}, Emit: prop.EmitConst},
+			"SupportedMimeTypes":  {Value: []string{}, Emit: prop.EmitConst},
+		},
+		playerIface: {
+			"PlaybackStatus": ro(playbackStatus(s.status.State)),
+			"Rate": {
+				Value:    cmp.Or(s.status.Speed, 1),
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: s.onRate,
+			},
+			"MinimumRate": {Value: player.MinSpeed, Emit: prop.EmitConst},
+			"MaximumRate": {Value: player.MaxSpeed, Emit: prop.EmitConst},
+			"Metadata":    ro(s.metadata),
+			"Volume": {
+				Value:    float64(s.status.Volume) / 100,
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: s.onVolume,
+			},
+			"Position":      ro(microseconds(s.status.Info.Position)),
+			"CanGoNext":     ro(true),
+			"CanGoPrevious": ro(s.status.Index > 0),
+			"CanPlay":       ro(true),
+			"CanPause":      ro(true),
+			"CanSeek":       ro(true),
+			"CanControl":    {Value: true, Emit: prop.EmitConst},
+		},
+	}
+}
+
+func (s *Server) onVolume(c *prop.Change) *dbus.Error {
+	volume, _ := c.Value.(float64)
+	if err := s.ctrl.SetVolume(int(volume*100 + 0.5)); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// onRate changes the speed; the spec asks to ignore a rate of 0 rather
+// than pause.
+func (s *Server) onRate(c *prop.Change) *dbus.Error {
+	rate, _ := c.Value.(float64)
+	if rate <= 0 {
+		return nil
+	}
+	if err := s.ctrl.SetSpeed(rate); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (s *Server) listen(events <-chan player.PlayerMsg) {
+	for msg := range events {
+		switch msg := msg.(type) {
+		case player.PlayerProgressMsg:
+			s.mu.Lock()
+			s.status.Info = player.PlayerInfo(msg)
+			meta, changed := s.renderMetadata()
+			s.mu.Unlock()
+			if changed {
+				s.props.SetMust(playerIface, "Metadata", meta)
+			}
+			s.props.SetMust(playerIface, "Position", microseconds(msg.Position))
+		case player.VolumeChangedMsg:
+			s.props.SetMust(playerIface, "Volume", float64(msg)/100)
+		case player.SpeedChangedMsg:
+			s.props.SetMust(playerIface, "Rate", float64(msg))
+		case player.PlayerStateChangedMsg, player.PlayStartedMsg, player.PlayStoppedMsg, player.QueueChangedMsg:
+			s.refresh()
+		}
+	}
+}
+
+// refresh pulls a fresh status from the controller and publishes whatever
+// changed.
+func (s *Server) refresh() {
+	status, err := s.ctrl.Status()
+	if err != nil {
+		return
+	}
+	queue, _ := s.ctrl.Queue()
+
+	s.mu.Lock()
+	previous := s.status
+	s.status = status
+	meta, changed := s.renderMetadata()
+	s.mu.Unlock()
+
+	if status.State != previous.State {
+		s.props.SetMust(playerIface, "PlaybackStatus", playbackStatus(status.State))
+	}
+	if changed {
+		s.props.SetMust(playerIface, "Metadata", meta)
+	}
+	s.props.SetMust(playerIface, "CanGoNext", status.Index+1 < len(queue))
+	s.props.SetMust(playerIface, "CanGoPrevious", status.Index > 0)
+}
+
+// renderMetadata builds the metadata of the current status and reports
+// whether it differs from the one last published. s.mu must be held.
+func (s *Server) renderMetadata() (map[string]dbus.Variant, bool) {
+	meta := metadata(s.status)
+	if reflect.DeepEqual(meta, s.metadata) {
+		return meta, false
+	}
+	s.metadata = meta
+	return meta, true
+}
+
+func (s *Server) seeked(pos float64) {
+	_ = s.conn.Emit(objectPath, playerIface+".Seeked", microseconds(pos))
+}
+
+func playerMethods(pl *playerObject) []introspect.Method {
+	methods := introspect.Methods(pl)
+	for i, m := range methods {
+		if name, ok := playerMethodNames[m.Name]; ok {
+			methods[i].Name = name
+		}
+	}
+	return methods
+}
+
+func playbackStatus(state string) string {
+	switch state {
+	case player.StateName(player.Playing), player.StateName(player.Loading):
+		return "Playing"
+	case player.StateName(player.Paused):
+		return "Paused"
+	}
+	return "Stopped"
+}
+
+func trackID(video *player.VideoInfo) dbus.ObjectPath {
+	if video == nil {
+		return noTrack
+	}
+	// Object path elements only allow [A-Za-z0-9_], YouTube IDs also use '-'.
+	return dbus.ObjectPath("/org/ghost_player/track/t" + hex.EncodeToString([]byte(video.ID)))
+}
+
+func metadata(status daemon.Status) map[string]dbus.Variant {
+	m := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackID(status.Track)),
+	}
+	video := status.Track
+	if video == nil {
+		return m
+	}
+	length := video.Duration
+	if length == 0 {
+		length = status.Info.Length
+	}
+	m["xesam:title"] = dbus.MakeVariant(video.Title)
+	m["xesam:artist"] = dbus.MakeVariant([]string{video.Uploader})
+	m["mpris:length"] = dbus.MakeVariant(microseconds(length))
+	if video.IsLocal() {
+		m["xesam:url"] = dbus.MakeVariant("file://" + video.Path)
+		return m
+	}
+	m["xesam:url"] = dbus.MakeVariant(player.WatchURL(video.ID))
+	m["mpris:artUrl"] = dbus.MakeVariant("https://i.ytimg.com/vi/" + video.ID + "/hqdefault.jpg")
+	return m
+}
+
+func microseconds(seconds float64) int64 {
+	return int64(seconds * 1e6)
+}
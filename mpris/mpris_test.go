@@ -0,0 +1,159 @@
+package mpris
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"player/daemon/daemontest"
+	"player/player"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// privateBus starts a dbus-daemon for the duration of the test and returns
+// its address.
+func privateBus(t *testing.T) string {
+	t.Helper()
+	bin, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command(bin, "--session", "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading bus address: %v", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+func connect(t *testing.T, addr string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatalf("dbus.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestMPRIS(t *testing.T) {
+	addr := privateBus(t)
+
+	ctrl := daemontest.New()
+	ctrl.Enqueue(player.VideoInfo{ID: "dQw4w9WgXcQ", Title: "Song", Uploader: "Artist", Duration: 212})
+	ctrl.PlayIndex(0)
+	ctrl.SetVolume(80)
+	s, err := New(connect(t, addr), ctrl)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.unsubscribe()
+
+	client := connect(t, addr)
+	obj := client.Object(BusName, objectPath)
+
+	t.Run("Metadata", func(t *testing.T) {
+		v, err := obj.GetProperty(playerIface + ".Metadata")
+		if err != nil {
+			t.Fatalf("GetProperty() error = %v", err)
+		}
+		m := v.Value().(map[string]dbus.Variant)
+		if got := m["xesam:title"].Value(); got != "Song" {
+			t.Errorf("xesam:title = %v, want Song", got)
+		}
+		if got := m["xesam:artist"].Value().([]string); len(got) != 1 || got[0] != "Artist" {
+			t.Errorf("xesam:artist = %v, want [Artist]", got)
+		}
+		if got := m["mpris:length"].Value(); got != int64(212_000_000) {
+			t.Errorf("mpris:length = %v, want 212000000", got)
+		}
+	})
+
+	t.Run("PlayPause", func(t *testing.T) {
+		if call := obj.Call(playerIface+".PlayPause", 0); call.Err != nil {
+			t.Fatalf("PlayPause error = %v", call.Err)
+		}
+		if !ctrl.Called("TogglePause") {
+			t.Error("PlayPause did not toggle pause")
+		}
+	})
+
+	t.Run("Volume", func(t *testing.T) {
+		if err := obj.SetProperty(playerIface+".Volume", dbus.MakeVariant(0.5)); err != nil {
+			t.Fatalf("SetProperty() error = %v", err)
+		}
+		if status, _ := ctrl.Status(); status.Volume != 50 {
+			t.Errorf("volume = %d, want 50", status.Volume)
+		}
+	})
+
+	t.Run("PropertiesChanged", func(t *testing.T) {
+		if err := client.AddMatchSignal(
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		); err != nil {
+			t.Fatal(err)
+		}
+		signals := make(chan *dbus.Signal, 16)
+		client.Signal(signals)
+
+		// PlayPause left the track paused.
+		ctrl.SetState("playing")
+
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case sig := <-signals:
+				changed, _ := sig.Body[1].(map[string]dbus.Variant)
+				// The pause may still be signalled first.
+				if status, ok := changed["PlaybackStatus"]; ok && status.Value() == "Playing" {
+					return
+				}
+			case <-timeout:
+				t.Fatal("no PropertiesChanged signal for PlaybackStatus Playing")
+			}
+		}
+	})
+
+	t.Run("MetadataFollowsProgress", func(t *testing.T) {
+		signals := make(chan *dbus.Signal, 16)
+		client.Signal(signals)
+
+		// A track without a known duration only learns it from mpv.
+		ctrl.Enqueue(player.VideoInfo{ID: "jNQXAC9IVRw", Title: "Live", Uploader: "Artist"})
+		ctrl.PlayIndex(1)
+		ctrl.Publish(player.PlayerProgressMsg{Position: 1, Length: 19})
+
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case sig := <-signals:
+				changed, _ := sig.Body[1].(map[string]dbus.Variant)
+				meta, ok := changed["Metadata"]
+				if !ok {
+					continue
+				}
+				m, _ := meta.Value().(map[string]dbus.Variant)
+				if length, ok := m["mpris:length"]; ok && length.Value() == int64(19_000_000) {
+					return
+				}
+			case <-timeout:
+				t.Fatal("no PropertiesChanged signal for the length learned from progress")
+			}
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package mpris
+
+import (
+	"errors"
+
+	"player/player"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// rootObject implements org.mpris.MediaPlayer2.
+type rootObject struct {
+	s *Server
+}
+
+func (r *rootObject) Raise() *dbus.Error { return nil }
+func (r *rootObject) Quit() *dbus.Error  { return nil }
+
+// playerObject implements org.mpris.MediaPlayer2.Player.
+type playerObject struct {
+	s *Server
+}
+
+var playerMethodNames = map[string]string{"SeekBy": "Seek"}
+
+func (p *playerObject) state() string {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+	return p.s.status.State
+}
+
+func (p *playerObject) Next() *dbus.Error {
+	return dbusError(p.s.ctrl.Next())
+}
+
+func (p *playerObject) Previous() *dbus.Error {
+	return dbusError(p.s.ctrl.Previous())
+}
+
+func (p *playerObject) Pause() *dbus.Error {
+	if p.state() != player.StateName(player.Playing) {
+		return nil
+	}
+	return dbusError(p.s.ctrl.TogglePause())
+}
+
+func (p *playerObject) Play() *dbus.Error {
+	switch p.state() {
+	case player.StateName(player.Paused):
+		return dbusError(p.s.ctrl.TogglePause())
+	case player.StateName(player.Stopped):
+		status, err := p.s.ctrl.Status()
+		if err != nil {
+			return dbusError(err)
+		}
+		return dbusError(p.s.ctrl.PlayIndex(max(status.Index, 0)))
+	}
+	return nil
+}
+
+func (p *playerObject) PlayPause() *dbus.Error {
+	if p.state() == player.StateName(player.Stopped) {
+		return p.Play()
+	}
+	return dbusError(p.s.ctrl.TogglePause())
+}
+
+func (p *playerObject) Stop() *dbus.Error {
+	if p.state() == player.StateName(player.Stopped) {
+		return nil
+	}
+	return dbusError(p.s.ctrl.Stop())
+}
+
+// SeekBy is exported on the bus as Seek; the Go name avoids clashing with
+// io.Seeker's signature.
+func (p *playerObject) SeekBy(offset int64) *dbus.Error {
+	if err := p.s.ctrl.Seek(float64(offset) / 1e6); err != nil {
+		return dbusError(err)
+	}
+	p.s.mu.Lock()
+	pos := p.s.status.Info.Position + float64(offset)/1e6
+	p.s.mu.Unlock()
+	p.s.seeked(pos)
+	return nil
+}
+
+func (p *playerObject) SetPosition(track dbus.ObjectPath, pos int64) *dbus.Error {
+	p.s.mu.Lock()
+	current := trackID(p.s.status.Track)
+	p.s.mu.Unlock()
+	// The spec asks to ignore requests for a track that is no longer current.
+	if track != current {
+		return nil
+	}
+	if err := p.s.ctrl.SetPosition(float64(pos) / 1e6); err != nil {
+		return dbusError(err)
+	}
+	p.s.seeked(float64(pos) / 1e6)
+	return nil
+}
+
+func (p *playerObject) OpenUri(uri string) *dbus.Error {
+	return dbus.MakeFailedError(errors.New("OpenUri is not supported"))
+}
+
+func dbusError(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	return dbus.MakeFailedError(err)
+}
@@ -0,0 +1,52 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "ghost_player"
+
+// RuntimeDir returns the directory for sockets and other per-boot files.
+func RuntimeDir() string {
+	return ensure(xdg("XDG_RUNTIME_DIR", os.TempDir()))
+}
+
+// DataDir returns the directory for the library and other persistent data.
+func DataDir() string {
+	return ensure(xdg("XDG_DATA_HOME", filepath.Join(home(), ".local", "share")))
+}
+
+// ConfigDir returns the directory holding the user configuration.
+func ConfigDir() string {
+	return ensure(xdg("XDG_CONFIG_HOME", filepath.Join(home(), ".config")))
+}
+
+// StateDir returns the directory for logs and session state.
+func StateDir() string {
+	return ensure(xdg("XDG_STATE_HOME", filepath.Join(home(), ".local", "state")))
+}
+
+// CacheDir returns the directory for downloaded, disposable files.
+func CacheDir() string {
+	return ensure(xdg("XDG_CACHE_HOME", filepath.Join(home(), ".cache")))
+}
+
+func xdg(env, fallback string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+	return filepath.Join(fallback, appName)
+}
+
+func home() string {
+	if dir, err := os.UserHomeDir(); err == nil {
+		return dir
+	}
+	return os.TempDir()
+}
+
+func ensure(dir string) string {
+	_ = os.MkdirAll(dir, 0o700)
+	return dir
+}
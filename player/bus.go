@@ -0,0 +1,131 @@
+package player
+
+import (
+	"slices"
+	"sync"
+)
+
+// lagLimit is how many messages a subscriber may fall behind before lossy
+// messages are dropped for it.
+const lagLimit = 64
+
+// Bus delivers player messages to any number of subscribers. Publishing
+// never blocks: each subscriber has its own queue drained into its channel
+// by a goroutine. When a subscriber lags, a new progress message replaces
+// the pending one and output lines and log records are dropped; every
+// other message is delivered, in order.
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[*subscriber]struct{}
+	closed bool
+}
+
+type subscriber struct {
+	mu      sync.Mutex
+	pending []PlayerMsg
+	wake    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	out     chan PlayerMsg
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*subscriber]struct{})}
+}
+
+// Subscribe returns a channel receiving every message published from now
+// on and a function ending the subscription, which closes the channel.
+func (b *Bus) Subscribe() (<-chan PlayerMsg, func()) {
+	s := &subscriber{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+		out:  make(chan PlayerMsg),
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(s.out)
+		return s.out, func() {}
+	}
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	go s.pump()
+	return s.out, func() {
+		b.mu.Lock()
+		delete(b.subs, s)
+		b.mu.Unlock()
+		s.stop()
+	}
+}
+
+func (b *Bus) Publish(msg PlayerMsg) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		s.push(msg)
+	}
+}
+
+// Close ends every subscription; later ones get a closed channel.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		s.stop()
+	}
+}
+
+func (s *subscriber) push(msg PlayerMsg) {
+	s.mu.Lock()
+	switch msg.(type) {
+	case PlayerProgressMsg:
+		s.pending = slices.DeleteFunc(s.pending, func(m PlayerMsg) bool {
+			_, ok := m.(PlayerProgressMsg)
+			return ok
+		})
+	case PlayerOutputMsg, LogMsg:
+		if len(s.pending) >= lagLimit {
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.pending = append(s.pending, msg)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) pump() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		msg := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- msg:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *subscriber) stop() {
+	s.once.Do(func() { close(s.done) })
+}
@@ -0,0 +1,86 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan PlayerMsg) PlayerMsg {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func TestBusDeliversToEverySubscriber(t *testing.T) {
+	b := NewBus()
+	first, _ := b.Subscribe()
+	second, _ := b.Subscribe()
+
+	b.Publish(PlayStartedMsg{VideoID: "a"})
+	for _, ch := range []<-chan PlayerMsg{first, second} {
+		if got := receive(t, ch); got != (PlayStartedMsg{VideoID: "a"}) {
+			t.Errorf("received %v, want the started message", got)
+		}
+	}
+}
+
+func TestBusCoalescesProgressOfLaggingSubscribers(t *testing.T) {
+	b := NewBus()
+	ch, _ := b.Subscribe()
+
+	// Nothing reads while these are published.
+	b.Publish(PlayerStateChangedMsg("playing"))
+	for i := range 3 * lagLimit {
+		b.Publish(PlayerProgressMsg{Progress: i % 100})
+		b.Publish(PlayerOutputMsg("line"))
+	}
+	b.Publish(PlayStoppedMsg{})
+
+	var progress, output int
+	if got := receive(t, ch); got != PlayerStateChangedMsg("playing") {
+		t.Fatalf("first message = %v, want the state change", got)
+	}
+	for {
+		msg := receive(t, ch)
+		switch msg.(type) {
+		case PlayerProgressMsg:
+			progress++
+		case PlayerOutputMsg:
+			output++
+		}
+		if msg == (PlayStoppedMsg{}) {
+			break
+		}
+	}
+	if progress != 1 {
+		t.Errorf("received %d progress messages, want 1", progress)
+	}
+	if output > lagLimit {
+		t.Errorf("received %d output lines, want at most %d", output, lagLimit)
+	}
+}
+
+func TestBusUnsubscribeAndClose(t *testing.T) {
+	b := NewBus()
+	ch, unsubscribe := b.Subscribe()
+	b.Publish(PlayStoppedMsg{})
+	unsubscribe()
+	unsubscribe()
+	for range ch {
+	}
+
+	other, _ := b.Subscribe()
+	b.Close()
+	if _, ok := <-other; ok {
+		t.Error("channel still open after Close")
+	}
+	late, _ := b.Subscribe()
+	if _, ok := <-late; ok {
+		t.Error("subscribing to a closed bus should return a closed channel")
+	}
+}
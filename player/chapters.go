@@ -0,0 +1,134 @@
+package player
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Chapter is a part of a track, like a song of a mix. Times are in seconds.
+type Chapter struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// ChaptersMsg carries the chapters of the track VideoID once they are known.
+type ChaptersMsg struct {
+	VideoID  string    `json:"video_id"`
+	Chapters []Chapter `json:"chapters"`
+}
+
+const (
+	// chapterSlack covers mpv landing a little before a chapter it seeks to.
+	chapterSlack = 0.5
+	// chapterRestart is how far into a chapter going back restarts it
+	// rather than moving to the previous one.
+	chapterRestart = 3
+)
+
+// chapterMetadata is the yt-dlp output template of the fields chapters are
+// read from.
+const chapterMetadata = "%(.{duration,description,chapters})j"
+
+// parseMetadataChapters reads the chapters out of the chapterMetadata of a
+// video, from its description when none are listed.
+func parseMetadataChapters(metadata string) ([]Chapter, error) {
+	var meta struct {
+		Duration    float64 `json:"duration"`
+		Description string  `json:"description"`
+		Chapters    []struct {
+			Title string  `json:"title"`
+			Start float64 `json:"start_time"`
+			End   float64 `json:"end_time"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+		return nil, err
+	}
+	if len(meta.Chapters) == 0 {
+		return ParseChapters(meta.Description, meta.Duration), nil
+	}
+	chapters := make([]Chapter, len(meta.Chapters))
+	for i, c := range meta.Chapters {
+		chapters[i] = Chapter{Title: c.Title, Start: c.Start, End: c.End}
+	}
+	return chapters, nil
+}
+
+// timestamp matches a chapter start like 4:05, 04:05 or 1:04:05.
+var timestamp = regexp.MustCompile(`\b(?:\d{1,2}:)?\d{1,2}:\d{2}\b`)
+
+// titleTrim are the characters around a timestamp that are not part of the
+// chapter title.
+const titleTrim = " \t-–—|:•·[]()"
+
+// ParseChapters reads a tracklist of "0:00 Title" lines out of a video
+// description. Like YouTube it wants the first one at 0:00 and the others
+// in order, so that timestamps quoted in the text are not taken for one.
+// length ends the last chapter; it may be 0 when unknown.
+func ParseChapters(description string, length float64) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		clock := timestamp.FindString(line)
+		if clock == "" {
+			continue
+		}
+		start := parseClock(clock)
+		if len(chapters) == 0 && start != 0 {
+			continue
+		}
+		// The tracklist ends at a time out of order or past the end.
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start || length > 0 && start >= length {
+			break
+		}
+		// Ranges like "0:00 - 3:12 Title" only keep their title.
+		title := strings.Trim(timestamp.ReplaceAllString(line, ""), titleTrim)
+		chapters = append(chapters, Chapter{Title: title, Start: start})
+	}
+	if len(chapters) < 2 {
+		return nil
+	}
+	for i := range chapters {
+		if i+1 < len(chapters) {
+			chapters[i].End = chapters[i+1].Start
+		} else {
+			chapters[i].End = length
+		}
+	}
+	return chapters
+}
+
+// ChapterAt is the index of the chapter playing at position, -1 without
+// chapters.
+func ChapterAt(chapters []Chapter, position float64) int {
+	index := -1
+	for i, c := range chapters {
+		if position+chapterSlack >= c.Start {
+			index = i
+		}
+	}
+	return index
+}
+
+// NextChapter is the start of the chapter after the one at position.
+func NextChapter(chapters []Chapter, position float64) (float64, bool) {
+	i := ChapterAt(chapters, position) + 1
+	if i >= len(chapters) {
+		return 0, false
+	}
+	return chapters[i].Start, true
+}
+
+// PreviousChapter is the start of the chapter at position, or of the one
+// before when the current one has only just begun.
+func PreviousChapter(chapters []Chapter, position float64) (float64, bool) {
+	i := ChapterAt(chapters, position)
+	if i < 0 {
+		return 0, false
+	}
+	if position-chapters[i].Start < chapterRestart && i > 0 {
+		i--
+	}
+	return chapters[i].Start, true
+}
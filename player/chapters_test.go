@@ -0,0 +1,115 @@
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChapters(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		length      float64
+		want        []Chapter
+	}{
+		{
+			name: "tracklist",
+			description: `Summer mix, best part at 12:30!
+
+Tracklist:
+0:00 Intro
+[04:15] Artist - Song
+1:02:03 - Outro
+Follow me on 1:1:1`,
+			length: 4000,
+			want: []Chapter{
+				{Title: "Intro", Start: 0, End: 255},
+				{Title: "Artist - Song", Start: 255, End: 3723},
+				{Title: "Outro", Start: 3723, End: 4000},
+			},
+		},
+		{
+			name:        "ranges",
+			description: "00:00 - 03:12 First\n03:12 - 07:00 Second",
+			want: []Chapter{
+				{Title: "First", Start: 0, End: 192},
+				{Title: "Second", Start: 192, End: 0},
+			},
+		},
+		{
+			name:        "ends out of order",
+			description: "0:00 One\n2:00 Two\nmy favourite is at 1:30",
+			length:      300,
+			want: []Chapter{
+				{Title: "One", Start: 0, End: 120},
+				{Title: "Two", Start: 120, End: 300},
+			},
+		},
+		{
+			name:        "not from the start",
+			description: "1:00 One\n2:00 Two",
+		},
+		{
+			name:        "single timestamp",
+			description: "0:00 Full album",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseChapters(tt.description, tt.length); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseChapters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChapterNavigation(t *testing.T) {
+	chapters := []Chapter{
+		{Title: "A", Start: 0, End: 60},
+		{Title: "B", Start: 60, End: 120},
+		{Title: "C", Start: 120, End: 180},
+	}
+	if got := ChapterAt(chapters, 59.8); got != 1 {
+		t.Errorf("ChapterAt(59.8) = %d, want 1 just before B", got)
+	}
+	if got := ChapterAt(nil, 10); got != -1 {
+		t.Errorf("ChapterAt(nil) = %d, want -1", got)
+	}
+
+	if start, ok := NextChapter(chapters, 70); !ok || start != 120 {
+		t.Errorf("NextChapter(70) = %g, %v, want 120", start, ok)
+	}
+	if _, ok := NextChapter(chapters, 150); ok {
+		t.Error("NextChapter in the last chapter should find none")
+	}
+
+	if start, ok := PreviousChapter(chapters, 90); !ok || start != 60 {
+		t.Errorf("PreviousChapter(90) = %g, %v, want the start of B", start, ok)
+	}
+	if start, ok := PreviousChapter(chapters, 61); !ok || start != 0 {
+		t.Errorf("PreviousChapter(61) = %g, %v, want the start of A", start, ok)
+	}
+	if start, ok := PreviousChapter(chapters, 1); !ok || start != 0 {
+		t.Errorf("PreviousChapter(1) = %g, %v, want 0", start, ok)
+	}
+}
+
+func TestParseMetadataChapters(t *testing.T) {
+	listed := `{"duration": 600, "description": "0:00 A\n5:00 B", "chapters": [{"title": "Intro", "start_time": 0, "end_time": 90}, {"title": "Main", "start_time": 90, "end_time": 600}]}`
+	chapters, err := parseMetadataChapters(listed)
+	want := []Chapter{{Title: "Intro", Start: 0, End: 90}, {Title: "Main", Start: 90, End: 600}}
+	if err != nil || !reflect.DeepEqual(chapters, want) {
+		t.Errorf("listed chapters = %+v, %v, want %+v", chapters, err, want)
+	}
+
+	described := `{"duration": 600, "description": "0:00 A\n5:00 B", "chapters": null}`
+	chapters, err = parseMetadataChapters(described)
+	want = []Chapter{{Title: "A", Start: 0, End: 300}, {Title: "B", Start: 300, End: 600}}
+	if err != nil || !reflect.DeepEqual(chapters, want) {
+		t.Errorf("described chapters = %+v, %v, want %+v", chapters, err, want)
+	}
+
+	if _, err := parseMetadataChapters("NA"); err == nil {
+		t.Error("parseMetadataChapters(NA) should fail")
+	}
+}
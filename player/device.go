@@ -0,0 +1,105 @@
+package player
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AutoDevice lets mpv pick the audio output.
+const AutoDevice = "auto"
+
+// AudioDevice is an audio output of mpv.
+type AudioDevice struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// AudioDevicesChangedMsg is sent when mpv sees outputs come or go.
+type AudioDevicesChangedMsg []AudioDevice
+
+// deviceLine matches a device of "mpv --audio-device=help":
+//
+//	'alsa/default' (Default ALSA Output)
+var deviceLine = regexp.MustCompile(`^\s*'(.+)' \((.*)\)\s*$`)
+
+// AudioDevices lists the outputs mpv can play to, asking the running mpv
+// or, when nothing plays, a short-lived one. The list is kept for
+// KnownDevices.
+func (p *Player) AudioDevices() ([]AudioDevice, error) {
+	if pipe := p.pipe(); pipe != "" {
+		raw, err := p.requestAt(pipe, "get_property", "audio-device-list")
+		if err == nil {
+			var devices []AudioDevice
+			if err := json.Unmarshal(raw, &devices); err == nil {
+				p.do(func() { p.devices = devices })
+				return devices, nil
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, p.bin, "--no-config", "--audio-device=help").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing audio devices: %w", err)
+	}
+	var devices []AudioDevice
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := deviceLine.FindStringSubmatch(line); m != nil {
+			devices = append(devices, AudioDevice{Name: m[1], Description: m[2]})
+		}
+	}
+	p.do(func() { p.devices = devices })
+	return devices, nil
+}
+
+// KnownDevices returns the outputs listed last, only asking mpv for them
+// the first time. The running mpv keeps them current.
+func (p *Player) KnownDevices() ([]AudioDevice, error) {
+	var devices []AudioDevice
+	p.do(func() { devices = slices.Clone(p.devices) })
+	if devices != nil {
+		return devices, nil
+	}
+	return p.AudioDevices()
+}
+
+// AudioDevice is the output in use, AutoDevice unless one was chosen.
+func (p *Player) AudioDevice() string {
+	var device string
+	p.do(func() { device = p.device })
+	return device
+}
+
+// SetAudioDevice switches the output, at once if a track plays.
+func (p *Player) SetAudioDevice(name string) error {
+	if name == "" {
+		name = AutoDevice
+	}
+	return p.call(func() error {
+		p.device = name
+		if p.proc == nil {
+			return nil
+		}
+		return p.command("set_property", "audio-device", name)
+	})
+}
+
+// HasDevice reports whether devices holds name; AutoDevice is always there.
+func HasDevice(devices []AudioDevice, name string) bool {
+	if name == AutoDevice {
+		return true
+	}
+	for _, d := range devices {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDeviceMpv lists two devices for --audio-device=help and otherwise
+// records its arguments in the returned file.
+func fakeDeviceMpv(t *testing.T, p *Player) string {
+	t.Helper()
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	script := `#!/bin/sh
+for arg; do
+	if [ "$arg" = "--audio-device=help" ]; then
+		echo "List of detected audio devices:"
+		echo "  'auto' (Autoselect device)"
+		echo "  'pulse/headphones' (Headphones (USB))"
+		exit 0
+	fi
+done
+echo "$@" > ` + args + `
+`
+	bin := filepath.Join(dir, "mpv")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p.bin = bin
+	return args
+}
+
+func TestAudioDevicesWhenIdle(t *testing.T) {
+	p := NewPlayer()
+	args := fakeDeviceMpv(t, p)
+
+	devices, err := p.AudioDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AudioDevice{{"auto", "Autoselect device"}, {"pulse/headphones", "Headphones (USB)"}}
+	if len(devices) != len(want) || devices[0] != want[0] || devices[1] != want[1] {
+		t.Fatalf("AudioDevices() = %v, want %v", devices, want)
+	}
+	if !HasDevice(devices, "pulse/headphones") || HasDevice(devices, "alsa/hdmi") {
+		t.Error("HasDevice() does not follow the list")
+	}
+
+	if err := p.SetAudioDevice("pulse/headphones"); err != nil {
+		t.Fatal(err)
+	}
+	p.PlayCmd(VideoInfo{ID: "file:/a.mp3", Path: "/a.mp3"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(args)
+		if strings.Contains(string(data), "--audio-device=pulse/headphones") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mpv arguments = %q, want the chosen device", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestKnownDevicesAreCached(t *testing.T) {
+	p := NewPlayer()
+	fakeDeviceMpv(t, p)
+
+	if _, err := p.KnownDevices(); err != nil {
+		t.Fatal(err)
+	}
+	// Listed once, the devices no longer need mpv.
+	p.bin = filepath.Join(t.TempDir(), "missing")
+	devices, err := p.KnownDevices()
+	if err != nil || !HasDevice(devices, "pulse/headphones") {
+		t.Errorf("KnownDevices() = %v, %v, want the cached list", devices, err)
+	}
+	if _, err := p.AudioDevices(); err == nil {
+		t.Error("AudioDevices() without mpv succeeded, want it to list again")
+	}
+}
@@ -0,0 +1,144 @@
+package player
+
+import (
+	"errors"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// ErrorKind classifies why a track could not be played.
+type ErrorKind string
+
+const (
+	// ErrResolve is a yt-dlp failure without a more precise cause.
+	ErrResolve        ErrorKind = "resolve"
+	ErrGeoBlocked     ErrorKind = "geo_blocked"
+	ErrAgeRestricted  ErrorKind = "age_restricted"
+	ErrBackendMissing ErrorKind = "backend_missing"
+	ErrNetwork        ErrorKind = "network"
+	// ErrDecode is mpv failing on the media itself.
+	ErrDecode ErrorKind = "decode"
+)
+
+var errorLabels = map[ErrorKind]string{
+	ErrResolve:        "flux introuvable",
+	ErrGeoBlocked:     "bloqué dans ce pays",
+	ErrAgeRestricted:  "restreint par âge",
+	ErrBackendMissing: "mpv ou yt-dlp introuvable",
+	ErrNetwork:        "erreur réseau",
+	ErrDecode:         "format illisible",
+}
+
+// Label describes the kind for the user.
+func (k ErrorKind) Label() string {
+	if label, ok := errorLabels[k]; ok {
+		return label
+	}
+	return string(k)
+}
+
+// Retryable reports whether trying the same track again may succeed.
+func (k ErrorKind) Retryable() bool {
+	return k == ErrResolve || k == ErrNetwork
+}
+
+// PlaybackError is carried by PlayErrorMsg when a track fails to play.
+type PlaybackError struct {
+	Kind    ErrorKind
+	VideoID string
+	// Detail is the message of yt-dlp or mpv.
+	Detail string
+}
+
+func (e *PlaybackError) Error() string {
+	if e.Detail == "" {
+		return e.Kind.Label()
+	}
+	return e.Kind.Label() + ": " + e.Detail
+}
+
+func (e *PlaybackError) Retryable() bool {
+	return e.Kind.Retryable()
+}
+
+// Markers of yt-dlp and mpv messages, matched case-insensitively.
+var (
+	geoMarkers = []string{"available in your country", "geo restrict", "blocked it in your country"}
+	ageMarkers = []string{"confirm your age", "age-restricted", "age restricted", "inappropriate for some users"}
+	netMarkers = []string{
+		"unable to download webpage", "timed out", "name resolution", "network is unreachable",
+		"connection reset", "connection refused", "http error 403", "http error 5", "getaddrinfo",
+	}
+)
+
+// resolveError classifies a failure of yt-dlp from what it printed.
+func resolveError(id string, err error, stderr string) *PlaybackError {
+	kind := ErrResolve
+	lower := strings.ToLower(stderr + " " + err.Error())
+	switch {
+	case missingExecutable(err):
+		kind = ErrBackendMissing
+	case containsAny(lower, geoMarkers):
+		kind = ErrGeoBlocked
+	case containsAny(lower, ageMarkers):
+		kind = ErrAgeRestricted
+	case containsAny(lower, netMarkers):
+		kind = ErrNetwork
+	}
+	return &PlaybackError{Kind: kind, VideoID: id, Detail: lastLine(stderr, err)}
+}
+
+// backendError classifies mpv exiting with err after printing stderr.
+func backendError(id string, err error, stderr string) *PlaybackError {
+	kind := ErrDecode
+	switch {
+	case missingExecutable(err):
+		kind = ErrBackendMissing
+	case containsAny(strings.ToLower(stderr), netMarkers):
+		kind = ErrNetwork
+	}
+	return &PlaybackError{Kind: kind, VideoID: id, Detail: lastLine(stderr, err)}
+}
+
+func missingExecutable(err error) bool {
+	return errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, m := range markers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastLine is the last line of output, where both tools put the reason,
+// or the error itself.
+func lastLine(output string, err error) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if line := strings.TrimSpace(lines[len(lines)-1]); line != "" {
+		return line
+	}
+	return err.Error()
+}
+
+// tailWriter keeps the last bytes written to it, for the stderr of mpv.
+type tailWriter struct {
+	buf []byte
+}
+
+const tailSize = 4096
+
+func (w *tailWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	if len(w.buf) > tailSize {
+		w.buf = w.buf[len(w.buf)-tailSize:]
+	}
+	return len(b), nil
+}
+
+func (w *tailWriter) String() string {
+	return string(w.buf)
+}
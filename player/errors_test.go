@@ -0,0 +1,49 @@
+package player
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestResolveErrorKinds(t *testing.T) {
+	tests := []struct {
+		stderr string
+		err    error
+		want   ErrorKind
+	}{
+		{"ERROR: [youtube] abc: The uploader has not made this video available in your country", errors.New("exit status 1"), ErrGeoBlocked},
+		{"ERROR: [youtube] abc: Sign in to confirm your age. This video may be inappropriate for some users.", errors.New("exit status 1"), ErrAgeRestricted},
+		{"ERROR: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>", errors.New("exit status 1"), ErrNetwork},
+		{"ERROR: [youtube] abc: Video unavailable", errors.New("exit status 1"), ErrResolve},
+		{"", exec.ErrNotFound, ErrBackendMissing},
+	}
+	for _, tt := range tests {
+		got := resolveError("abc", tt.err, tt.stderr)
+		if got.Kind != tt.want {
+			t.Errorf("resolveError(%q).Kind = %s, want %s", tt.stderr, got.Kind, tt.want)
+		}
+		if got.VideoID != "abc" {
+			t.Errorf("VideoID = %q, want abc", got.VideoID)
+		}
+	}
+}
+
+func TestPlaybackErrorEventRoundTrip(t *testing.T) {
+	want := &PlaybackError{Kind: ErrNetwork, VideoID: "abc", Detail: "HTTP Error 503"}
+	e, err := EncodeEvent(PlayErrorMsg{Err: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := DecodeEvent(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got *PlaybackError
+	if !errors.As(msg.(PlayErrorMsg).Err, &got) || *got != *want {
+		t.Fatalf("decoded %#v, want %#v", msg, want)
+	}
+	if !got.Retryable() {
+		t.Error("network errors should be retryable")
+	}
+}
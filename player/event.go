@@ -0,0 +1,138 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Event is the wire form of a PlayerMsg shared by every remote API.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type errorPayload struct {
+	Message string `json:"message"`
+	// The fields of a PlaybackError, if the error is one.
+	Kind      ErrorKind `json:"kind,omitempty"`
+	VideoID   string    `json:"video_id,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	Retryable bool      `json:"retryable,omitempty"`
+}
+
+func newErrorPayload(err error) errorPayload {
+	payload := errorPayload{Message: err.Error()}
+	var pe *PlaybackError
+	if errors.As(err, &pe) {
+		payload.Kind = pe.Kind
+		payload.VideoID = pe.VideoID
+		payload.Detail = pe.Detail
+		payload.Retryable = pe.Retryable()
+	}
+	return payload
+}
+
+func (e errorPayload) err() error {
+	if e.Kind != "" {
+		return &PlaybackError{Kind: e.Kind, VideoID: e.VideoID, Detail: e.Detail}
+	}
+	return errors.New(e.Message)
+}
+
+func EncodeEvent(msg PlayerMsg) (Event, error) {
+	var (
+		kind string
+		data any
+	)
+	switch msg := msg.(type) {
+	case PlayerProgressMsg:
+		kind, data = "progress", PlayerInfo(msg)
+	case PlayerStateChangedMsg:
+		kind, data = "state", string(msg)
+	case PlayerOutputMsg:
+		kind, data = "output", string(msg)
+	case PlayerErrorMsg:
+		kind, data = "error", errorPayload{Message: msg.Error()}
+	case PlayStartedMsg:
+		kind, data = "started", msg
+	case PlayErrorMsg:
+		kind, data = "play_error", newErrorPayload(msg.Err)
+	case PlayStoppedMsg:
+		kind = "stopped"
+	case QueueChangedMsg:
+		kind, data = "queue", msg
+	case VolumeChangedMsg:
+		kind, data = "volume", int(msg)
+	case SpeedChangedMsg:
+		kind, data = "speed", float64(msg)
+	case LogMsg:
+		kind, data = "log", msg
+	case ChaptersMsg:
+		kind, data = "chapters", msg
+	default:
+		return Event{}, fmt.Errorf("unknown player message %T", msg)
+	}
+
+	e := Event{Type: kind}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return Event{}, err
+		}
+		e.Data = raw
+	}
+	return e, nil
+}
+
+func DecodeEvent(e Event) (PlayerMsg, error) {
+	switch e.Type {
+	case "progress":
+		var info PlayerInfo
+		err := json.Unmarshal(e.Data, &info)
+		return PlayerProgressMsg(info), err
+	case "state":
+		var state string
+		err := json.Unmarshal(e.Data, &state)
+		return PlayerStateChangedMsg(state), err
+	case "output":
+		var line string
+		err := json.Unmarshal(e.Data, &line)
+		return PlayerOutputMsg(line), err
+	case "error":
+		var payload errorPayload
+		err := json.Unmarshal(e.Data, &payload)
+		return PlayerErrorMsg(errors.New(payload.Message)), err
+	case "started":
+		var msg PlayStartedMsg
+		err := json.Unmarshal(e.Data, &msg)
+		return msg, err
+	case "play_error":
+		var payload errorPayload
+		err := json.Unmarshal(e.Data, &payload)
+		return PlayErrorMsg{Err: payload.err()}, err
+	case "stopped":
+		return PlayStoppedMsg{}, nil
+	case "queue":
+		var msg QueueChangedMsg
+		err := json.Unmarshal(e.Data, &msg)
+		return msg, err
+	case "volume":
+		var volume int
+		err := json.Unmarshal(e.Data, &volume)
+		return VolumeChangedMsg(volume), err
+	case "speed":
+		var speed float64
+		err := json.Unmarshal(e.Data, &speed)
+		return SpeedChangedMsg(speed), err
+	case "log":
+		var msg LogMsg
+		err := json.Unmarshal(e.Data, &msg)
+		return msg, err
+	case "chapters":
+		var msg ChaptersMsg
+		err := json.Unmarshal(e.Data, &msg)
+		return msg, err
+	}
+	return nil, fmt.Errorf("unknown event type %q", e.Type)
+}
@@ -0,0 +1,145 @@
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// codecFilters are the yt-dlp format filters of the codecs that can be
+// preferred.
+var codecFilters = map[string]string{
+	"opus":   "[acodec=opus]",
+	"aac":    "[acodec^=mp4a]",
+	"vorbis": "[acodec=vorbis]",
+	"mp3":    "[acodec=mp3]",
+}
+
+// Codecs lists the codecs a FormatPolicy may prefer.
+func Codecs() []string {
+	codecs := make([]string, 0, len(codecFilters))
+	for codec := range codecFilters {
+		codecs = append(codecs, codec)
+	}
+	slices.Sort(codecs)
+	return codecs
+}
+
+// FormatPolicy chooses the stream of an online track.
+type FormatPolicy struct {
+	// Codec is preferred when a stream of it is available; empty for any.
+	Codec string `json:"codec,omitempty"`
+	// MaxBitrate in kbps is not exceeded when a stream under it exists,
+	// for metered connections; 0 for no cap.
+	MaxBitrate int `json:"max_bitrate,omitempty"`
+	// Fallback are yt-dlp format specs tried before the defaults when the
+	// preferred streams are missing.
+	Fallback []string `json:"fallback,omitempty"`
+}
+
+func (f FormatPolicy) Validate() error {
+	if _, ok := codecFilters[f.Codec]; f.Codec != "" && !ok {
+		return fmt.Errorf("unknown codec %q, want one of %s", f.Codec, strings.Join(Codecs(), ", "))
+	}
+	if f.MaxBitrate < 0 {
+		return fmt.Errorf("negative bitrate cap %d", f.MaxBitrate)
+	}
+	return nil
+}
+
+// Selector is the yt-dlp format selection of the policy: the preferred
+// codec under the cap, any codec under the cap, the fallbacks, then the
+// smallest stream if capped and the best one.
+func (f FormatPolicy) Selector() string {
+	var capped string
+	if f.MaxBitrate > 0 {
+		// "?" lets through the streams whose bitrate is unknown.
+		capped = fmt.Sprintf("[abr<=?%d]", f.MaxBitrate)
+	}
+	var chain []string
+	if f.Codec != "" {
+		chain = append(chain, "bestaudio"+codecFilters[f.Codec]+capped)
+	}
+	chain = append(chain, "bestaudio"+capped)
+	chain = append(chain, f.Fallback...)
+	if capped != "" {
+		chain = append(chain, "worstaudio")
+	}
+	chain = append(chain, "bestaudio", "best")
+
+	var unique []string
+	for _, spec := range chain {
+		if !slices.Contains(unique, spec) {
+			unique = append(unique, spec)
+		}
+	}
+	return strings.Join(unique, "/")
+}
+
+// AudioFormat is the stream mpv decodes, for the now playing detail.
+type AudioFormat struct {
+	Codec string `json:"codec,omitempty"`
+	// Bitrate is in kbps.
+	Bitrate    int `json:"bitrate,omitempty"`
+	SampleRate int `json:"sample_rate,omitempty"`
+}
+
+// String reads like "opus 160k 48kHz", leaving out what is unknown.
+func (a AudioFormat) String() string {
+	var parts []string
+	if a.Codec != "" {
+		parts = append(parts, a.Codec)
+	}
+	if a.Bitrate > 0 {
+		parts = append(parts, fmt.Sprintf("%dk", a.Bitrate))
+	}
+	if a.SampleRate > 0 {
+		parts = append(parts, strings.TrimSuffix(fmt.Sprintf("%.1f", float64(a.SampleRate)/1000), ".0")+"kHz")
+	}
+	return strings.Join(parts, " ")
+}
+
+// probeTries and probeInterval bound how long the format is asked for:
+// mpv knows the bitrate only after decoding a little.
+const (
+	probeTries    = 5
+	probeInterval = time.Second
+)
+
+// probeAudio asks the mpv of session for its audio format until the
+// bitrate is known, and reports it with the progress.
+func (p *Player) probeAudio(session int, pipe string) {
+	for range probeTries {
+		var format AudioFormat
+		if raw, err := p.requestAt(pipe, "get_property", "audio-codec-name"); err == nil {
+			_ = json.Unmarshal(raw, &format.Codec)
+		}
+		var bitrate, rate float64
+		if raw, err := p.requestAt(pipe, "get_property", "audio-bitrate"); err == nil {
+			_ = json.Unmarshal(raw, &bitrate)
+		}
+		if raw, err := p.requestAt(pipe, "get_property", "audio-params/samplerate"); err == nil {
+			_ = json.Unmarshal(raw, &rate)
+		}
+		format.Bitrate = int(bitrate/1000 + 0.5)
+		format.SampleRate = int(rate)
+
+		current := true
+		p.do(func() {
+			if p.session != session || p.proc == nil {
+				current = false
+				return
+			}
+			if format != p.info.Audio {
+				p.info.Audio = format
+				p.bus.Publish(PlayerProgressMsg(p.info))
+			}
+		})
+		if !current || format.Bitrate > 0 {
+			return
+		}
+		time.Sleep(probeInterval)
+	}
+}
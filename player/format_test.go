@@ -0,0 +1,45 @@
+package player
+
+import "testing"
+
+func TestFormatSelector(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy FormatPolicy
+		want   string
+	}{
+		{"default", FormatPolicy{}, "bestaudio/best"},
+		{"codec", FormatPolicy{Codec: "opus"}, "bestaudio[acodec=opus]/bestaudio/best"},
+		{"capped", FormatPolicy{Codec: "aac", MaxBitrate: 96},
+			"bestaudio[acodec^=mp4a][abr<=?96]/bestaudio[abr<=?96]/worstaudio/bestaudio/best"},
+		{"fallback", FormatPolicy{Codec: "opus", Fallback: []string{"251", "140"}},
+			"bestaudio[acodec=opus]/bestaudio/251/140/best"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Selector(); got != tt.want {
+			t.Errorf("%s: Selector() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatValidate(t *testing.T) {
+	if err := (FormatPolicy{Codec: "flac"}).Validate(); err == nil {
+		t.Error("unknown codec accepted")
+	}
+	if err := (FormatPolicy{MaxBitrate: -1}).Validate(); err == nil {
+		t.Error("negative cap accepted")
+	}
+	if err := (FormatPolicy{Codec: "opus", MaxBitrate: 128}).Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestAudioFormatString(t *testing.T) {
+	format := AudioFormat{Codec: "opus", Bitrate: 160, SampleRate: 48000}
+	if got := format.String(); got != "opus 160k 48kHz" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (AudioFormat{Codec: "aac", SampleRate: 44100}).String(); got != "aac 44.1kHz" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"player/paths"
+)
+
+// socketPath is the IPC socket of an mpv session. The name carries the pid
+// of the player so the sockets of a crashed one can be told apart.
+func socketPath(session int) string {
+	return filepath.Join(paths.RuntimeDir(), fmt.Sprintf("mpv-%d-%d.sock", os.Getpid(), session))
+}
+
+// CleanOrphans stops the mpv processes left running by players that died
+// without stopping them, found through their sockets, and removes stale
+// sockets. It returns how many processes were stopped.
+func CleanOrphans() (int, error) {
+	dir := paths.RuntimeDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	stopped := 0
+	for _, entry := range entries {
+		var owner, session int
+		if _, err := fmt.Sscanf(entry.Name(), "mpv-%d-%d.sock", &owner, &session); err != nil {
+			continue
+		}
+		if owner == os.Getpid() || processAlive(owner) {
+			continue
+		}
+		sock := filepath.Join(dir, entry.Name())
+		if stopOrphan(sock) {
+			stopped++
+		}
+		_ = os.Remove(sock)
+	}
+	return stopped, nil
+}
+
+// stopOrphan asks the mpv behind sock for its pid and kills its group. A
+// socket nobody listens on is only stale.
+func stopOrphan(sock string) bool {
+	raw, err := ipcRequest(sock, 1, "get_property", "pid")
+	if err != nil {
+		return false
+	}
+	var pid int
+	if err := json.Unmarshal(raw, &pid); err != nil || pid <= 0 {
+		_, err := ipcRequest(sock, 2, "quit")
+		return err == nil
+	}
+	return killGroup(pid) == nil
+}
+
+// killGroup kills an mpv started with its own process group, and whatever
+// it spawned.
+func killGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
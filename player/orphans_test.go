@@ -0,0 +1,111 @@
+package player
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"player/paths"
+)
+
+// deadPid is the pid of a process that has exited.
+func deadPid(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+// fakeOrphan listens on sock like an mpv, answering every request with the
+// pid of a process running in its own group.
+func fakeOrphan(t *testing.T, sock string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+				fmt.Fprintf(conn, `{"request_id":1,"error":"success","data":%d}`+"\n", cmd.Process.Pid)
+			}
+			conn.Close()
+		}
+	}()
+	return cmd
+}
+
+func TestCleanOrphans(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	dir := paths.RuntimeDir()
+	dead := deadPid(t)
+
+	stale := filepath.Join(dir, fmt.Sprintf("mpv-%d-1.sock", dead))
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	live := filepath.Join(dir, fmt.Sprintf("mpv-%d-1.sock", os.Getppid()))
+	if err := os.WriteFile(live, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	orphan := fakeOrphan(t, filepath.Join(dir, fmt.Sprintf("mpv-%d-2.sock", dead)))
+	exited := make(chan struct{})
+	go func() {
+		_ = orphan.Wait()
+		close(exited)
+	}()
+
+	n, err := CleanOrphans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("stopped %d processes, want 1", n)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Error("orphan mpv still running")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale socket not removed")
+	}
+	if _, err := os.Stat(live); err != nil {
+		t.Error("socket of a running player removed")
+	}
+}
+
+func TestSocketIsPrivate(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	sock := socketPath(1)
+	if filepath.Dir(sock) != paths.RuntimeDir() {
+		t.Errorf("socket %s outside the runtime directory", sock)
+	}
+	info, err := os.Stat(filepath.Dir(sock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("runtime directory mode = %o, want 700", perm)
+	}
+}
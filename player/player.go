@@ -2,21 +2,30 @@ package player
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/url"
 	"os"
 	"os/exec"
-	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/lrstanley/go-ytdlp"
+	"github.com/dhowden/tag"
 )
+
 const (
 	Loading = iota
 	Stopped = iota
@@ -24,32 +33,154 @@ const (
 	Playing = iota
 )
 
+var stateNames = map[int]string{
+	Loading: "loading",
+	Stopped: "stopped",
+	Paused:  "paused",
+	Playing: "playing",
+}
+
+func StateName(state int) string {
+	return stateNames[state]
+}
+
+// Player drives mpv. Its state belongs to the goroutine running loop: the
+// methods hand it closures over reqs and mpv output arrives on events, so
+// they are safe to call from any goroutine but never from loop itself.
 type Player struct {
-	cancel context.CancelFunc
-	cmd    *exec.Cmd
-	ctx    context.Context
-	info   PlayerInfo
-	ch     chan PlayerMsg
-	pipe    string
+	reqs      chan func()
+	events    chan procEvent
+	bus       *Bus
+	requestID atomic.Int64
+	// bin is the mpv executable.
+	bin string
+
+	proc *process
+	// fading is the previous track while the next one starts over it,
+	// until its mpv exits.
+	fading *process
+	// session numbers the mpv processes; events of an older one are
+	// ignored once a new one has started or the player was stopped.
+	session int
+	info    PlayerInfo
 	state   int
 	stream  string
+	// chapters are those yt-dlp listed for the stream.
+	chapters []Chapter
+	volume   int
+	speed    float64
+	filters  []filter
+	// replaygain is mpv's replaygain option: no, track or album.
+	replaygain string
+	crossfade  float64
+	ending     bool
+	// fadeIn is the crossfade into the next track played.
+	fadeIn float64
+	format FormatPolicy
+	// device is the audio output, AutoDevice for mpv's choice.
+	device string
+	// devices are the outputs listed last; the running mpv keeps them
+	// current.
+	devices []AudioDevice
+}
+
+// process is one running mpv.
+type process struct {
+	session int
+	pid     int
+	videoID string
+	cancel  context.CancelFunc
+	pipe    string
+	// fadeIn is how long the volume ramps up once playback starts.
+	fadeIn float64
+	// paused is set for a track loaded paused, until it is loaded.
+	paused bool
+	// started gets nil once mpv plays, or why it stopped before.
+	started chan error
+	// next is the track queued in mpv after this one, and playlistPos
+	// the playlist entry mpv plays.
+	next        *preloaded
+	playlistPos int
+}
+
+// preloaded is a track resolved ahead and appended to the playlist of the
+// running mpv.
+type preloaded struct {
+	video    VideoInfo
+	stream   string
+	chapters []Chapter
+}
+
+// procEvent is a line printed by an mpv or, with exited set, its end
+// and why it failed if it did.
+type procEvent struct {
+	session int
+	line    string
+	exited  bool
+	err     *PlaybackError
+}
+
+var progressRegex = regexp.MustCompile(`A:\s+(\d{2}:(\d{2}:\d{2}))\s+/\s+(\d{2}:(\d{2}:\d{2}))\s+\((\d+)%\)`)
+
+// Playback speed limits; mpv keeps the pitch within them.
+const (
+	MinSpeed = 0.5
+	MaxSpeed = 3.0
+)
+
+// rampStep is the interval between two volume changes of a fade.
+const rampStep = 100 * time.Millisecond
+
+// filter is one labelled entry of the mpv af chain.
+type filter struct {
+	label string
+	spec  string
 }
 
 type PlayerInfo struct {
-	Duration string
-	Current  string
-	Progress int
+	Duration string  `json:"duration"`
+	Current  string  `json:"current"`
+	Progress int     `json:"progress"`
+	Position float64 `json:"position"`
+	Length   float64 `json:"length"`
+	// Speed is the playback rate, 1 being real time.
+	Speed float64     `json:"speed,omitempty"`
+	Audio AudioFormat `json:"audio"`
+	// VideoID is the track the info is about.
+	VideoID string `json:"video_id,omitempty"`
+}
+
+// Remaining is the wall clock time left in the track at its speed.
+func (i PlayerInfo) Remaining() float64 {
+	return max(i.Length-i.Position, 0) / cmp.Or(i.Speed, 1)
 }
+
 type PlayStoppedMsg struct{}
+
+// PlayerEndingMsg is sent once the current track is within the crossfade
+// duration of its end.
+type PlayerEndingMsg struct {
+	Remaining float64
+}
 type PlayerErrorMsg error
 type PlayerStateChangedMsg string
 type PlayerOutputMsg string
+type VolumeChangedMsg int
+type SpeedChangedMsg float64
+
+// LogMsg is a record of the log, published for the log pane.
+type LogMsg struct {
+	Time      time.Time  `json:"time"`
+	Level     slog.Level `json:"level"`
+	Component string     `json:"component,omitempty"`
+	Message   string     `json:"message"`
+}
 
 var currentPlayer *Player
 
 type PlayStartedMsg struct {
-	VideoID string
-	Title   string
+	VideoID string `json:"video_id"`
+	Title   string `json:"title"`
 }
 
 type PlayErrorMsg struct {
@@ -62,191 +193,742 @@ type VideoInfo struct {
 	Duration float64 `json:"duration"`
 	Uploader string  `json:"uploader"`
 	URL      string  `json:"url"`
+	Path     string  `json:"path,omitempty"`
+	Album    string  `json:"album,omitempty"`
+	// Chapters are known once the video was resolved.
+	Chapters []Chapter `json:"chapters,omitempty"`
+	// Playlist and PlaylistIndex place a video in the playlist it was
+	// listed from.
+	Playlist      string `json:"playlist_id,omitempty"`
+	PlaylistIndex int    `json:"playlist_index,omitempty"`
+	// Failed is set by the queue on a track that could not be played.
+	Failed ErrorKind `json:"failed,omitempty"`
+
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+// Thumbnail is one of the preview images yt-dlp lists for a video, from
+// the least to the most preferred.
+type Thumbnail struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// LocalTrack describes an audio file on disk so it can be queued like a video.
+func LocalTrack(file string) (VideoInfo, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return VideoInfo{}, err
+	}
+	st, err := os.Stat(abs)
+	if err != nil {
+		return VideoInfo{}, err
+	}
+	if st.IsDir() {
+		return VideoInfo{}, fmt.Errorf("%s is a directory", file)
+	}
+	name := filepath.Base(abs)
+	video := VideoInfo{
+		ID:    "file:" + abs,
+		Title: strings.TrimSuffix(name, filepath.Ext(name)),
+		Path:  abs,
+	}
+	if f, err := os.Open(abs); err == nil {
+		defer f.Close()
+		if m, err := tag.ReadFrom(f); err == nil {
+			if m.Title() != "" {
+				video.Title = m.Title()
+			}
+			video.Uploader = cmp.Or(m.AlbumArtist(), m.Artist())
+			video.Album = m.Album()
+		}
+	}
+	return video, nil
+}
+
+// SameRelease reports whether v and o are tracks of the same album, or o
+// follows v in a playlist. Those play gapless and must not be crossfaded.
+func (v VideoInfo) SameRelease(o VideoInfo) bool {
+	if v.Playlist != "" && v.Playlist == o.Playlist && o.PlaylistIndex == v.PlaylistIndex+1 {
+		return true
+	}
+	return v.Album != "" && v.Album == o.Album && v.Uploader == o.Uploader
+}
+
+func (v VideoInfo) IsLocal() bool {
+	return v.Path != ""
+}
+
+// Location is the file path of a local track or the watch URL of a video.
+func (v VideoInfo) Location() string {
+	if v.IsLocal() {
+		return v.Path
+	}
+	return WatchURL(v.ID)
 }
 
 type TrackItem struct {
 	Info VideoInfo
+	// Heard is the share of the track already played, 0 when unknown.
+	Heard float64
 }
 
 func (t TrackItem) Title() string       { return t.Info.Title }
-func (t TrackItem) Description() string { return t.Info.Uploader }
 func (t TrackItem) FilterValue() string { return t.Info.Title }
 
-type SearchCompleteMsg struct {
-	Results []VideoInfo
-	Err     error
+// Description is the uploader, behind the reason of a failure if any.
+func (t TrackItem) Description() string {
+	if t.Info.Failed != "" {
+		return "✗ " + t.Info.Failed.Label() + " · " + t.Info.Uploader
+	}
+	return t.Info.Uploader
 }
+
 type (
 	PlayerMsg         interface{}
 	PlayerProgressMsg PlayerInfo
 )
 
 func NewPlayer() *Player {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Player{
-		cancel: cancel,
-		ctx:    ctx,
-		ch:     make(chan PlayerMsg, 10),
+	p := &Player{
+		reqs:   make(chan func()),
+		events: make(chan procEvent, 64),
+		bus:    NewBus(),
+		bin:    "mpv",
 		state:  Stopped,
+		volume: 100,
+		speed:  1,
+		device: AutoDevice,
 	}
+	go p.loop()
+	return p
 }
 
-func SearchYTCmd(query string, maxRes int) tea.Cmd {
-	return func() tea.Msg {
-		results, err := SearchYoutube(query, maxRes)
-		return SearchCompleteMsg{
-			results,
-			err,
+func (p *Player) loop() {
+	for {
+		select {
+		case fn := <-p.reqs:
+			fn()
+		case e := <-p.events:
+			switch {
+			case e.exited && p.fading != nil && e.session == p.fading.session:
+				p.fading = nil
+			case p.proc == nil || e.session != p.proc.session:
+			case e.exited:
+				p.exited(e.err)
+			default:
+				p.handleLine(e.line)
+			}
 		}
 	}
 }
 
-func (p *Player) PlayCmd(video VideoInfo) {
-		if p.state != Stopped {
-				_ = p.Stop()
-			}
+// do runs fn on the loop goroutine and waits for it.
+func (p *Player) do(fn func()) {
+	done := make(chan struct{})
+	p.reqs <- func() {
+		defer close(done)
+		fn()
+	}
+	<-done
+}
 
-		streamURL, err := getStreamURL(video.ID)
-		if err != nil {
-		}
+func (p *Player) call(fn func() error) error {
+	var err error
+	p.do(func() { err = fn() })
+	return err
+}
+
+// PlayCmd replaces the current track with video. The stream is resolved
+// outside the loop, so a Stop or another PlayCmd meanwhile wins. It returns
+// once mpv plays, or with why the track did not start; the error is
+// published as a PlayErrorMsg too.
+func (p *Player) PlayCmd(video VideoInfo) error {
+	return p.play(video, playOptions{})
+}
+
+// PlayFrom plays video from position seconds.
+func (p *Player) PlayFrom(video VideoInfo, position float64) error {
+	return p.play(video, playOptions{start: position})
+}
 
-		p.pipe = path.Join(os.TempDir(), "mpvsocket")
+// Resume loads video paused at position seconds, as left by a previous
+// session.
+func (p *Player) Resume(video VideoInfo, position float64) error {
+	return p.play(video, playOptions{start: position, paused: true})
+}
 
-		p.ctx, p.cancel = context.WithCancel(context.Background())
+// errReplaced is returned for a track stopped or replaced before it played.
+var errReplaced = errors.New("replaced before it played")
 
-		p.cmd = exec.CommandContext(p.ctx, "mpv",
-			streamURL,
-			"--no-video",
-			"--ytdl-format=bestaudio",
-			fmt.Sprintf("--input-ipc-server=%s", p.pipe),
-			"--quiet",
-		)
+// playOptions are how a track starts.
+type playOptions struct {
+	start  float64
+	paused bool
+	// fadeIn is how long the track fades in over the previous one.
+	fadeIn float64
+}
 
-			stdout, err := p.cmd.StdoutPipe()
+func (p *Player) play(video VideoInfo, opts playOptions) error {
+	var (
+		session  int
+		selector string
+	)
+	p.do(func() {
+		selector = p.format.Selector()
+		opts.fadeIn, p.fadeIn = p.fadeIn, 0
+		switch {
+		case p.proc != nil && opts.fadeIn > 0:
+			// The current track plays on while the next one resolves.
+			if p.fading != nil {
+				p.fading.cancel()
+			}
+			p.fading = p.proc
+		case p.proc != nil:
+			p.proc.cancel()
+		}
+		p.proc = nil
+		p.session++
+		session = p.session
+		p.stream = ""
+		p.chapters = nil
+		p.info = PlayerInfo{Speed: p.speed}
+		p.ending = false
+		p.setState(Loading)
+	})
+
+	streamURL := video.Path
+	var (
+		chapters []Chapter
+		err      error
+	)
+	if !video.IsLocal() {
+		streamURL, chapters, err = getStreamURL(video.ID, selector)
+	}
+
+	var started chan error
+	p.do(func() {
+		if p.session != session {
+			err = errReplaced
+			return
+		}
+		if err == nil {
+			err = p.start(session, video.ID, streamURL, opts)
+		}
+		if err == nil {
+			p.chapters = chapters
+			started = p.proc.started
+		}
+		if err != nil {
+			if opts.fadeIn == 0 || !p.keepFading() {
+				p.setState(Stopped)
+			}
+			p.bus.Publish(PlayErrorMsg{Err: err})
+		}
+	})
 	if err != nil {
-		p.ch <- PlayerErrorMsg(fmt.Errorf("error creating stdout pipe: %v", err))
-		return
+		return err
 	}
+	return <-started
+}
 
-	progressRegex := regexp.MustCompile(`A:\s+\d{2}:(\d{2}:\d{2})\s+/\s+\d{2}:(\d{2}:\d{2})\s+\((\d+)%\)`)
+// keepFading goes back to the track a failed one was to fade over, which
+// plays on at the full volume. It reports whether there was one.
+func (p *Player) keepFading() bool {
+	if p.fading == nil {
+		return false
+	}
+	p.proc, p.fading = p.fading, nil
+	pipe, volume := p.proc.pipe, p.volume
+	go func() { _, _ = p.requestAt(pipe, "set_property", "volume", volume) }()
+	p.info = PlayerInfo{Speed: p.speed, VideoID: p.proc.videoID}
+	p.setState(Playing)
+	return true
+}
 
-	if err := p.cmd.Start(); err != nil {
-		p.ch <- PlayerErrorMsg(fmt.Errorf("Error starting command: %v\n", err))
-		return
+// start runs mpv on streamURL and forwards its output as events of
+// session.
+func (p *Player) start(session int, videoID, streamURL string, opts playOptions) error {
+	pipe := socketPath(session)
+	proc := &process{session: session, videoID: videoID, pipe: pipe, fadeIn: opts.fadeIn, paused: opts.paused, started: make(chan error, 1)}
+
+	// A track following a crossfade starts silent and ramps up once mpv
+	// reports it is playing.
+	volume := p.volume
+	if proc.fadeIn > 0 {
+		volume = 0
+	}
+
+	args := []string{
+		"--no-video",
+		"--ytdl-format=" + p.format.Selector(),
+		fmt.Sprintf("--input-ipc-server=%s", pipe),
+		"--quiet",
+		fmt.Sprintf("--volume=%d", volume),
+		"--speed=" + strconv.FormatFloat(p.speed, 'f', -1, 64),
+		// A preloaded track is opened before the current one ends.
+		"--prefetch-playlist=yes",
+	}
+	if opts.start > 0 {
+		args = append(args, "--start="+strconv.FormatFloat(opts.start, 'f', -1, 64))
+	}
+	if opts.paused {
+		args = append(args, "--pause")
 	}
+	if p.device != AutoDevice {
+		args = append(args, "--audio-device="+p.device)
+	}
+	if managed := ManagedYtdlp(); isExecutable(managed) {
+		args = append(args, "--script-opts=ytdl_hook-ytdl_path="+managed)
+	}
+	if p.replaygain != "" {
+		args = append(args, "--replaygain="+p.replaygain)
+	}
+	if chain := p.filterChain(); chain != "" {
+		args = append(args, "--af="+chain)
+	}
+	// A stream starting with a dash stays a file to mpv.
+	args = append(args, "--", streamURL)
 
-	p.setState(Loading)
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, p.bin, args...)
+	// In its own process group mpv and the yt-dlp it runs are killed
+	// together, and a Ctrl-C in the terminal does not reach them.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return killGroup(cmd.Process.Pid) }
+	stderr := &tailWriter{}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("error creating stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return backendError(videoID, err, "")
+	}
+	proc.cancel = cancel
+	proc.pid = cmd.Process.Pid
+	logger("player").Debug("mpv started", "video", videoID, "pid", proc.pid, "socket", pipe)
+	p.proc = proc
+	p.stream = streamURL
 
 	go func() {
+		defer cancel()
+		defer os.Remove(pipe)
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
-			line := scanner.Text()
-			matches := progressRegex.FindStringSubmatch(line)
-			if len(matches) > 3 {
-				if p.state == Loading {
-					p.setState(Playing)
-				}
-				progress, _ := strconv.Atoi(matches[3])
-				if progress != p.info.Progress && progress < 100 {
-					p.info = PlayerInfo{
-						Current:  matches[1],
-						Duration: matches[2],
-						Progress: progress,
-					}
-					select {
-					case p.ch <- PlayerProgressMsg(p.info):
-					default:
-					}
-				}
-			} else {
-				p.ch <- PlayerOutputMsg(line)
-			}
+			p.events <- procEvent{session: session, line: scanner.Text()}
+		}
+		e := procEvent{session: session, exited: true}
+		failed := errors.New("mpv exited before playing")
+		if err := cmd.Wait(); err != nil {
+			e.err = backendError(videoID, err, stderr.String())
+			failed = e.err
+		}
+		p.events <- e
+		// Sent once the loop took the event, so after any status line
+		// that reported the start.
+		select {
+		case proc.started <- failed:
+		default:
 		}
 	}()
+	return nil
+}
 
-	go func() {
-		defer os.Remove(p.pipe)
-		err := p.cmd.Wait()
-		if err != nil {
-			p.setState(Stopped)
-			return
+// handleLine parses a status line of the current mpv.
+func (p *Player) handleLine(line string) {
+	matches := progressRegex.FindStringSubmatch(line)
+	if len(matches) <= 5 {
+		logger("player").Debug(line)
+		p.bus.Publish(PlayerOutputMsg(line))
+		return
+	}
+	if p.state == Loading {
+		// mpv creates the socket with the umask; the runtime directory is
+		// private already, this covers a shared fallback.
+		_ = os.Chmod(p.proc.pipe, 0o600)
+		if p.proc.paused {
+			p.setState(Paused)
+		} else {
+			p.setState(Playing)
+		}
+		p.proc.started <- nil
+		go p.probeAudio(p.proc.session, p.proc.pipe)
+		go p.watch(p.proc.session, p.proc.pipe)
+		if p.proc.fadeIn > 0 {
+			go p.ramp(p.proc.pipe, 0, p.volume, p.proc.fadeIn)
+			if p.fading != nil {
+				pipe, cancel, volume, seconds := p.fading.pipe, p.fading.cancel, p.volume, p.proc.fadeIn
+				go func() {
+					p.ramp(pipe, volume, 0, seconds)
+					cancel()
+				}()
+			}
 		}
+	}
+	progress, _ := strconv.Atoi(matches[5])
+	if progress >= 100 {
+		return
+	}
+	// Info follows every status line, listeners only hear about whole
+	// percents.
+	changed := progress != p.info.Progress
+	p.info = PlayerInfo{
+		Current:  matches[2],
+		Duration: matches[4],
+		Progress: progress,
+		Position: parseClock(matches[1]),
+		Length:   parseClock(matches[3]),
+		Speed:    p.speed,
+		Audio:    p.info.Audio,
+		VideoID:  p.proc.videoID,
+	}
+	if changed {
+		p.bus.Publish(PlayerProgressMsg(p.info))
+	}
+	// Seeking back out of the last seconds rearms the message.
+	remaining := p.info.Remaining()
+	ending := p.crossfade > 0 && p.info.Length > 0 && remaining <= p.crossfade
+	if ending && !p.ending {
+		p.bus.Publish(PlayerEndingMsg{Remaining: remaining})
+	}
+	p.ending = ending
+}
+
+// exited handles the end of the current mpv: a failure, or the end of the
+// track which is reported as full progress.
+func (p *Player) exited(err *PlaybackError) {
+	videoID, fadeIn := p.proc.videoID, p.proc.fadeIn
+	p.proc = nil
+	if err != nil {
+		logger("player").Warn("mpv failed", "video", err.VideoID, "kind", err.Kind, "detail", err.Detail)
+		p.bus.Publish(PlayErrorMsg{Err: err})
+	}
+	if p.state == Loading && fadeIn > 0 && p.keepFading() {
+		return
+	}
+	if err == nil {
 		p.info = PlayerInfo{
 			Progress: 100,
 			Current:  p.info.Duration,
 			Duration: p.info.Duration,
+			Position: p.info.Length,
+			Length:   p.info.Length,
+			Speed:    p.speed,
+			VideoID:  videoID,
 		}
-		p.ch <- PlayerProgressMsg(p.info)
-	}()
+		p.bus.Publish(PlayerProgressMsg(p.info))
+	}
+	p.setState(Stopped)
 }
 
-func (p *Player) Ch() chan PlayerMsg {
-	return p.ch
+// Subscribe returns the messages of the player, see Bus.Subscribe.
+func (p *Player) Subscribe() (<-chan PlayerMsg, func()) {
+	return p.bus.Subscribe()
 }
 
 func (p *Player) Info() PlayerInfo {
-	return p.info
+	var info PlayerInfo
+	p.do(func() { info = p.info })
+	return info
 }
 
-func (p *Player) Stop() error {
-	if p.state == Stopped {
-		err := fmt.Errorf("Player already stopped")
-		p.ch <- PlayerErrorMsg(err)
-		return err
-	}
-	p.setState(Stopped)
-	p.cancel()
-	return nil
+// Chapters are the chapters yt-dlp listed for the video playing.
+func (p *Player) Chapters() []Chapter {
+	var chapters []Chapter
+	p.do(func() { chapters = slices.Clone(p.chapters) })
+	return chapters
 }
 
-func StopCmd() tea.Cmd {
-	return func() tea.Msg {
-		return PlayStoppedMsg{}
-	}
+// Stream is the media URL or file mpv is playing.
+// Current is the ID of the track mpv plays or loads, empty while stopped.
+func (p *Player) Current() string {
+	var id string
+	p.do(func() {
+		if p.proc != nil {
+			id = p.proc.videoID
+		}
+	})
+	return id
 }
 
-func IsPlaying() bool {
-	return currentPlayer != nil
+func (p *Player) Stream() string {
+	var stream string
+	p.do(func() { stream = p.stream })
+	return stream
 }
 
-func SearchYoutube(query string, maxResult int) ([]VideoInfo, error) {
-	ctx := context.Background()
+func (p *Player) State() int {
+	var state int
+	p.do(func() { state = p.state })
+	return state
+}
+
+// Seek moves the playback position by offset seconds.
+func (p *Player) Seek(offset float64) error {
+	return p.call(func() error {
+		if p.state == Stopped {
+			return fmt.Errorf("nothing to seek")
+		}
+		return p.command("seek", offset, "relative")
+	})
+}
+
+// SetPosition moves the playback position to pos seconds.
+func (p *Player) SetPosition(pos float64) error {
+	return p.call(func() error {
+		if p.state == Stopped {
+			return fmt.Errorf("nothing to seek")
+		}
+		return p.command("seek", pos, "absolute")
+	})
+}
+
+func (p *Player) Volume() int {
+	var volume int
+	p.do(func() { volume = p.volume })
+	return volume
+}
+
+// SetVolume sets the volume in percent, clamped to 0-100. It applies to the
+// current track and to the following ones.
+func (p *Player) SetVolume(volume int) error {
+	return p.call(func() error {
+		p.volume = max(0, min(volume, 100))
+		if p.proc == nil {
+			return nil
+		}
+		return p.command("set_property", "volume", p.volume)
+	})
+}
 
-	dl := ytdlp.New().FlatPlaylist().DumpJSON()
+func (p *Player) Speed() float64 {
+	var speed float64
+	p.do(func() { speed = p.speed })
+	return speed
+}
+
+// SetSpeed sets the playback rate, clamped to MinSpeed-MaxSpeed. Like the
+// volume it is kept for the following tracks.
+func (p *Player) SetSpeed(speed float64) error {
+	return p.call(func() error {
+		p.speed = max(MinSpeed, min(speed, MaxSpeed))
+		p.info.Speed = p.speed
+		if p.proc == nil {
+			return nil
+		}
+		return p.command("set_property", "speed", p.speed)
+	})
+}
 
-	searchQuery := fmt.Sprintf("ytsearch%d:%s", maxResult, query)
-	result, err := dl.Run(ctx, searchQuery)
+// SetFilter puts spec in the audio filter chain under label, replacing the
+// filter with the same label; an empty spec removes it. The chain is kept
+// for the following tracks.
+func (p *Player) SetFilter(label, spec string) error {
+	return p.call(func() error {
+		i := slices.IndexFunc(p.filters, func(f filter) bool { return f.label == label })
+		switch {
+		case spec == "":
+			if i >= 0 {
+				p.filters = slices.Delete(p.filters, i, i+1)
+			}
+		case i >= 0:
+			p.filters[i].spec = spec
+		default:
+			p.filters = append(p.filters, filter{label: label, spec: spec})
+		}
+		if p.proc == nil {
+			return nil
+		}
+		return p.command("set_property", "af", p.filterChain())
+	})
+}
+
+// FilterMetadata returns what the audio filter labelled label attached to
+// the frames playing, like the measures of astats. It is nil while nothing
+// plays.
+func (p *Player) FilterMetadata(label string) (map[string]string, error) {
+	pipe := p.pipe()
+	if pipe == "" {
+		return nil, nil
+	}
+	raw, err := p.requestAt(pipe, "get_property", "af-metadata/"+label)
 	if err != nil {
-		return []VideoInfo{}, fmt.Errorf("search failed: %w", err)
+		return nil, err
 	}
+	var metadata map[string]string
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, fmt.Errorf("af-metadata: %w", err)
+	}
+	return metadata, nil
+}
 
-	var videos []VideoInfo
-	scanner := bufio.NewScanner(strings.NewReader(result.Stdout))
+// TimePos returns the playback position in seconds, finer than the one of
+// Info which mpv prints to the second.
+func (p *Player) TimePos() (float64, error) {
+	pipe := p.pipe()
+	if pipe == "" {
+		return 0, fmt.Errorf("nothing playing")
+	}
+	raw, err := p.requestAt(pipe, "get_property", "time-pos")
+	if err != nil {
+		return 0, err
+	}
+	var pos float64
+	if err := json.Unmarshal(raw, &pos); err != nil {
+		return 0, fmt.Errorf("time-pos: %w", err)
+	}
+	return pos, nil
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+// DumpCache writes what mpv has downloaded of the stream playing between
+// start and end seconds to file, as Matroska. Nothing is fetched again.
+func (p *Player) DumpCache(start, end float64, file string) error {
+	pipe := p.pipe()
+	if pipe == "" {
+		return fmt.Errorf("nothing playing")
+	}
+	_, err := p.requestAt(pipe, "dump-cache", start, end, file)
+	return err
+}
 
-		if strings.TrimSpace(line) == "" {
-			continue
+// pipe is the IPC socket of the mpv playing, empty while none is.
+func (p *Player) pipe() string {
+	var pipe string
+	p.do(func() {
+		if p.proc != nil {
+			pipe = p.proc.pipe
 		}
+	})
+	return pipe
+}
 
-		var video VideoInfo
-		if err := json.Unmarshal([]byte(line), &video); err != nil {
-			continue
+// SetReplayGain chooses which ReplayGain tags mpv applies: no, track or
+// album.
+func (p *Player) SetReplayGain(mode string) error {
+	return p.call(func() error {
+		p.replaygain = mode
+		if p.proc == nil {
+			return nil
 		}
+		return p.command("set_property", "replaygain", mode)
+	})
+}
 
-		videos = append(videos, video)
+func (p *Player) filterChain() string {
+	chain := make([]string, len(p.filters))
+	for i, f := range p.filters {
+		chain[i] = "@" + f.label + ":" + f.spec
 	}
+	return strings.Join(chain, ",")
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading output: %w", err)
+func (p *Player) TogglePause() error {
+	return p.call(func() error {
+		switch p.state {
+		case Playing:
+			if err := p.command("set_property", "pause", true); err != nil {
+				return err
+			}
+			p.setState(Paused)
+		case Paused:
+			if err := p.command("set_property", "pause", false); err != nil {
+				return err
+			}
+			p.setState(Playing)
+		default:
+			return fmt.Errorf("nothing to pause")
+		}
+		return nil
+	})
+}
+
+// SetCrossfade sets how many seconds before the end of a track
+// PlayerEndingMsg is sent; 0 disables it.
+func (p *Player) SetCrossfade(seconds float64) {
+	p.do(func() { p.crossfade = max(seconds, 0) })
+}
+
+// SetFormat sets the stream choice of the next tracks.
+func (p *Player) SetFormat(format FormatPolicy) error {
+	if err := format.Validate(); err != nil {
+		return err
 	}
+	p.do(func() { p.format = format })
+	return nil
+}
+
+// CrossfadeNext makes the next track played start over the current one.
+// The current track plays on until the next one is ready, then fades out
+// over seconds while the next one fades in.
+func (p *Player) CrossfadeNext(seconds float64) {
+	p.do(func() {
+		if p.proc != nil {
+			p.fadeIn = seconds
+		}
+	})
+}
+
+// ramp moves the volume of the mpv behind pipe from one value to another
+// over seconds. Failed steps are skipped: the socket may not be ready yet
+// or already gone.
+func (p *Player) ramp(pipe string, from, to int, seconds float64) {
+	steps := max(int(seconds/rampStep.Seconds()), 1)
+	for i := 1; i <= steps; i++ {
+		time.Sleep(rampStep)
+		_, _ = p.requestAt(pipe, "set_property", "volume", from+(to-from)*i/steps)
+	}
+}
+
+// Close kills the mpv processes at once, before the program exits.
+func (p *Player) Close() {
+	p.do(func() {
+		for _, proc := range []*process{p.proc, p.fading} {
+			if proc != nil {
+				_ = killGroup(proc.pid)
+				_ = os.Remove(proc.pipe)
+			}
+		}
+		p.proc, p.fading = nil, nil
+		p.session++
+		p.setState(Stopped)
+	})
+}
+
+func (p *Player) Stop() error {
+	return p.call(func() error {
+		if p.fading != nil {
+			p.fading.cancel()
+			p.fading = nil
+		}
+		p.fadeIn = 0
+		if p.state == Stopped {
+			err := fmt.Errorf("Player already stopped")
+			p.bus.Publish(PlayerErrorMsg(err))
+			return err
+		}
+		if p.proc != nil {
+			p.proc.cancel()
+			p.proc = nil
+		}
+		// Drop the events of the killed mpv and any stream being resolved.
+		p.session++
+		p.setState(Stopped)
+		return nil
+	})
+}
 
-	if len(videos) == 0 {
-		return nil, fmt.Errorf("no videos found")
+func StopCmd() tea.Cmd {
+	return func() tea.Msg {
+		return PlayStoppedMsg{}
 	}
+}
 
-	return videos, nil
+func IsPlaying() bool {
+	return currentPlayer != nil
 }
 
 func VideoToListeItem(videos []VideoInfo) []list.Item {
@@ -259,24 +941,83 @@ func VideoToListeItem(videos []VideoInfo) []list.Item {
 	return items
 }
 
-func getStreamURL(mediaId string) (string, error) {
+func WatchURL(id string) string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", id)
+}
+
+var videoIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// VideoFromURL extracts the video ID from a YouTube watch URL, a youtu.be
+// short link or a bare ID.
+func VideoFromURL(uri string) (VideoInfo, bool) {
+	id := uri
+	if u, err := url.Parse(uri); err == nil && u.Host != "" {
+		switch strings.TrimPrefix(u.Host, "www.") {
+		case "youtube.com", "m.youtube.com", "music.youtube.com":
+			id = u.Query().Get("v")
+		case "youtu.be":
+			id = strings.TrimPrefix(u.Path, "/")
+		}
+	}
+	if !videoIDRegex.MatchString(id) {
+		return VideoInfo{}, false
+	}
+	return VideoInfo{ID: id, URL: WatchURL(id)}, true
+}
+
+// getStreamURL resolves the stream of a video, and reads its chapters out of
+// the metadata yt-dlp has fetched for it anyway.
+func getStreamURL(mediaId, selector string) (string, []Chapter, error) {
 	ctx := context.Background()
-	mediaURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", mediaId)
+	mediaURL := WatchURL(mediaId)
+	log := logger("resolver")
+	log.Debug("resolving", "video", mediaId)
 
-	result, err := ytdlp.New().
-		Format("bestaudio/best").
-		GetURL().
+	// The metadata comes on the first line, then the URL.
+	result, err := Ytdlp().
+		Format(selector).
+		Print(chapterMetadata).
+		Print("urls").
 		NoWarnings().
 		Run(ctx, mediaURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to get stream URL: %w", err)
+		var stderr string
+		if result != nil {
+			stderr = result.Stderr
+		}
+		pe := resolveError(mediaId, err, stderr)
+		log.Warn("resolve failed", "video", mediaId, "kind", pe.Kind, "detail", pe.Detail)
+		log.Debug("yt-dlp output", "video", mediaId, "stderr", strings.TrimSpace(stderr))
+		return "", nil, pe
 	}
 
-	streamURL := strings.TrimSpace(result.Stdout)
+	metadata, streamURL, _ := strings.Cut(strings.TrimSpace(result.Stdout), "\n")
+	streamURL = strings.TrimSpace(streamURL)
 	if streamURL == "" {
-		return "", fmt.Errorf("empty stream URL")
+		log.Warn("resolve failed", "video", mediaId, "detail", "empty stream URL")
+		return "", nil, &PlaybackError{Kind: ErrResolve, VideoID: mediaId, Detail: "empty stream URL"}
+	}
+	chapters, err := parseMetadataChapters(metadata)
+	if err != nil {
+		log.Warn("reading chapters failed", "video", mediaId, "err", err)
 	}
-	return streamURL, nil
+	log.Debug("resolved", "video", mediaId, "chapters", len(chapters))
+	return streamURL, chapters, nil
+}
+
+// logger is the log of a part of the package: player, resolver or search.
+func logger(component string) *slog.Logger {
+	return slog.With("component", component)
+}
+
+// parseClock converts an mpv "hh:mm:ss" timestamp to seconds.
+func parseClock(clock string) float64 {
+	var seconds float64
+	for _, part := range strings.Split(clock, ":") {
+		n, _ := strconv.Atoi(part)
+		seconds = seconds*60 + float64(n)
+	}
+	return seconds
 }
 
 func (p *Player) setState(state int) {
@@ -284,23 +1025,63 @@ func (p *Player) setState(state int) {
 		return
 	}
 	p.state = state
-	p.ch <- PlayerStateChangedMsg(state)
+	logger("player").Debug("state changed", "state", StateName(state))
+	p.bus.Publish(PlayerStateChangedMsg(StateName(state)))
+}
+
+func (p *Player) command(args ...any) error {
+	if p.proc == nil {
+		return fmt.Errorf("nothing playing")
+	}
+	_, err := p.requestAt(p.proc.pipe, args...)
+	return err
 }
 
-func (p *Player) sendSocket(command string) error {
-	conn, err := net.Dial("unix", p.pipe)
+func (p *Player) requestAt(pipe string, args ...any) (json.RawMessage, error) {
+	return ipcRequest(pipe, p.requestID.Add(1), args...)
+}
+
+// ipcTimeout bounds a command to mpv, so a hung mpv cannot block the
+// player.
+const ipcTimeout = 2 * time.Second
+
+// ipcRequest sends one command over the mpv JSON IPC socket at pipe and
+// returns its data field. Events broadcast by mpv on the same connection
+// are skipped.
+func ipcRequest(pipe string, id int64, args ...any) (json.RawMessage, error) {
+	conn, err := net.DialTimeout("unix", pipe, ipcTimeout)
 	if err != nil {
-		return fmt.Errorf("Error connecting to socket: %v\n", err)
+		return nil, fmt.Errorf("error connecting to socket: %w", err)
 	}
 	defer conn.Close()
-	_, err = conn.Write([]byte(command + "\n"))
+	_ = conn.SetDeadline(time.Now().Add(ipcTimeout))
+
+	payload, err := json.Marshal(map[string]any{"command": args, "request_id": id})
 	if err != nil {
-		return fmt.Errorf("Error writing to socket: %v\n", err)
+		return nil, err
 	}
+	if _, err := conn.Write(append(payload, '\n')); err != nil {
+		return nil, fmt.Errorf("error writing to socket: %w", err)
+	}
+
 	reader := bufio.NewReader(conn)
-	_, err = reader.ReadString('\n')
-	if err != nil {
-		return fmt.Errorf("Failed to read response: %v\n", err)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		var resp struct {
+			Error     string          `json:"error"`
+			Data      json.RawMessage `json:"data"`
+			RequestID int64           `json:"request_id"`
+			Event     string          `json:"event"`
+		}
+		if err := json.Unmarshal(line, &resp); err != nil || resp.Event != "" || resp.RequestID != id {
+			continue
+		}
+		if resp.Error != "success" {
+			return nil, fmt.Errorf("mpv: %s", resp.Error)
+		}
+		return resp.Data, nil
 	}
-	return nil
 }
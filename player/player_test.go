@@ -1,7 +1,13 @@
 package player
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSearchYoutube(t *testing.T) {
@@ -44,3 +50,294 @@ func TestSearchYoutube(t *testing.T) {
 		t.Logf("Result %d: %s (ID: %s)", i+1, video.Title, video.ID)
 	}
 }
+
+func TestSetFilterChain(t *testing.T) {
+	p := NewPlayer()
+	p.SetFilter("eq", "lavfi=[equalizer=f=31:t=o:w=1:g=3]")
+	p.SetFilter("norm", "loudnorm")
+	p.SetFilter("eq", "lavfi=[equalizer=f=62:t=o:w=1:g=2]")
+
+	want := "@eq:lavfi=[equalizer=f=62:t=o:w=1:g=2],@norm:loudnorm"
+	if got := p.filterChain(); got != want {
+		t.Errorf("filterChain() = %q, want %q", got, want)
+	}
+
+	p.SetFilter("eq", "")
+	if got := p.filterChain(); got != "@norm:loudnorm" {
+		t.Errorf("after removal, filterChain() = %q", got)
+	}
+}
+
+func TestSameRelease(t *testing.T) {
+	track := VideoInfo{ID: "a", Uploader: "Artist", Album: "Album"}
+	tests := []struct {
+		name  string
+		other VideoInfo
+		want  bool
+	}{
+		{"same album", VideoInfo{ID: "b", Uploader: "Artist", Album: "Album"}, true},
+		{"other album", VideoInfo{ID: "b", Uploader: "Artist", Album: "Other"}, false},
+		{"other artist", VideoInfo{ID: "b", Uploader: "Someone", Album: "Album"}, false},
+		{"no album", VideoInfo{ID: "b", Uploader: "Artist"}, false},
+	}
+	for _, tt := range tests {
+		if got := track.SameRelease(tt.other); got != tt.want {
+			t.Errorf("%s: SameRelease() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	track = VideoInfo{ID: "a", Playlist: "PL1", PlaylistIndex: 3}
+	tests = []struct {
+		name  string
+		other VideoInfo
+		want  bool
+	}{
+		{"next in playlist", VideoInfo{ID: "b", Playlist: "PL1", PlaylistIndex: 4}, true},
+		{"further in playlist", VideoInfo{ID: "b", Playlist: "PL1", PlaylistIndex: 6}, false},
+		{"other playlist", VideoInfo{ID: "b", Playlist: "PL2", PlaylistIndex: 4}, false},
+	}
+	for _, tt := range tests {
+		if got := track.SameRelease(tt.other); got != tt.want {
+			t.Errorf("%s: SameRelease() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if (VideoInfo{}).SameRelease(VideoInfo{}) {
+		t.Error("tracks without album should not be the same release")
+	}
+}
+
+func TestRemainingFollowsSpeed(t *testing.T) {
+	info := PlayerInfo{Position: 60, Length: 360, Speed: 2}
+	if got := info.Remaining(); got != 150 {
+		t.Errorf("Remaining() = %g, want 150", got)
+	}
+	info.Speed = 0
+	if got := info.Remaining(); got != 300 {
+		t.Errorf("Remaining() without speed = %g, want 300", got)
+	}
+}
+
+// fakeMpv makes p run a script printing mpv status lines in place of mpv.
+func fakeMpv(t *testing.T, p *Player) {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "mpv")
+	script := `#!/bin/sh
+i=0
+while [ $i -lt 200 ]; do
+	echo "A: 00:00:0$((i % 10)) / 00:03:00 (1%)"
+	i=$((i + 1))
+	sleep 0.01
+done
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p.bin = bin
+}
+
+func waitState(t *testing.T, p *Player, state int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.State() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s, want %s", StateName(p.State()), StateName(state))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRapidPlayStop(t *testing.T) {
+	p := NewPlayer()
+	fakeMpv(t, p)
+	track := VideoInfo{ID: "file:/a.mp3", Path: "/a.mp3"}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.PlayCmd(track)
+			_ = p.Info()
+			_ = p.SetVolume(i * 5)
+			if i%2 == 0 {
+				_ = p.Stop()
+			}
+		}()
+	}
+	wg.Wait()
+
+	p.PlayCmd(track)
+	waitState(t, p, Playing)
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	// Lines and the exit of the killed mpv must not revive the state.
+	time.Sleep(100 * time.Millisecond)
+	if got := p.State(); got != Stopped {
+		t.Errorf("state after Stop = %s, want stopped", StateName(got))
+	}
+}
+
+func TestPlayReplacesCurrentTrack(t *testing.T) {
+	p := NewPlayer()
+	fakeMpv(t, p)
+
+	p.PlayCmd(VideoInfo{ID: "file:/a.mp3", Path: "/a.mp3"})
+	waitState(t, p, Playing)
+	p.PlayCmd(VideoInfo{ID: "file:/b.mp3", Path: "/b.mp3"})
+	if got := p.Stream(); got != "/b.mp3" {
+		t.Errorf("Stream() = %q, want /b.mp3", got)
+	}
+	waitState(t, p, Playing)
+	if info := p.Info(); info.Length != 180 {
+		t.Errorf("Info().Length = %g, want 180", info.Length)
+	}
+	_ = p.Stop()
+}
+
+func TestCrossfadeKeepsCurrentUntilNextPlays(t *testing.T) {
+	p := NewPlayer()
+	fakeMpv(t, p)
+	fading := func() *process {
+		var proc *process
+		p.do(func() { proc = p.fading })
+		return proc
+	}
+
+	p.PlayCmd(VideoInfo{ID: "file:/a.mp3", Path: "/a.mp3"})
+	waitState(t, p, Playing)
+	p.CrossfadeNext(0.2)
+	p.PlayCmd(VideoInfo{ID: "file:/b.mp3", Path: "/b.mp3"})
+	if fading() == nil {
+		t.Fatal("the current track was stopped before the next one played")
+	}
+	waitState(t, p, Playing)
+	deadline := time.Now().Add(5 * time.Second)
+	for fading() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the faded track is still kept after its end")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = p.Stop()
+}
+
+func TestFailedCrossfadeKeepsCurrent(t *testing.T) {
+	p := NewPlayer()
+	bin := filepath.Join(t.TempDir(), "mpv")
+	script := `#!/bin/sh
+case "$*" in
+*/b.mp3*) echo "Failed to open /b.mp3." >&2; exit 2 ;;
+esac
+while true; do
+	echo "A: 00:00:01 / 00:03:00 (1%)"
+	sleep 0.01
+done
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p.bin = bin
+
+	p.PlayCmd(VideoInfo{ID: "file:/a.mp3", Path: "/a.mp3"})
+	waitState(t, p, Playing)
+	p.CrossfadeNext(0.2)
+	if err := p.PlayCmd(VideoInfo{ID: "file:/b.mp3", Path: "/b.mp3"}); err == nil {
+		t.Fatal("PlayCmd() of a failing track succeeded")
+	}
+	if got := p.Current(); got != "file:/a.mp3" || p.State() != Playing {
+		t.Fatalf("Current() = %q in state %s, want the first track playing on", got, StateName(p.State()))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Info().VideoID != "file:/a.mp3" {
+		if time.Now().After(deadline) {
+			t.Fatal("the first track is no longer followed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = p.Stop()
+}
+
+func TestAdvanceToPreloadedTrack(t *testing.T) {
+	p := NewPlayer()
+	events, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+	next := VideoInfo{ID: "file:/b.mp3", Path: "/b.mp3"}
+	p.do(func() {
+		p.proc = &process{session: 1, videoID: "file:/a.mp3", next: &preloaded{video: next, stream: next.Path}}
+		p.advance(1, 0)
+	})
+	if got := p.Current(); got != "file:/a.mp3" {
+		t.Fatalf("Current() = %q before mpv moved on", got)
+	}
+	p.do(func() { p.advance(1, 1) })
+	if got, stream := p.Current(), p.Stream(); got != next.ID || stream != next.Path {
+		t.Errorf("Current() = %q, Stream() = %q, want the preloaded track", got, stream)
+	}
+	for msg := range events {
+		if msg, ok := msg.(TrackAdvancedMsg); ok {
+			if msg.Video.ID != next.ID {
+				t.Errorf("advanced to %q, want %q", msg.Video.ID, next.ID)
+			}
+			break
+		}
+	}
+	p.do(func() { p.proc = nil })
+}
+
+func TestBackendFailureIsReported(t *testing.T) {
+	p := NewPlayer()
+	p.bin = filepath.Join(t.TempDir(), "missing-mpv")
+	events, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+
+	p.PlayCmd(VideoInfo{ID: "file:/a.mp3", Path: "/a.mp3"})
+	for msg := range events {
+		failure, ok := msg.(PlayErrorMsg)
+		if !ok {
+			continue
+		}
+		var pe *PlaybackError
+		if !errors.As(failure.Err, &pe) || pe.Kind != ErrBackendMissing || pe.VideoID != "file:/a.mp3" {
+			t.Fatalf("error = %v, want a missing backend for file:/a.mp3", failure.Err)
+		}
+		break
+	}
+	if got := p.State(); got != Stopped {
+		t.Errorf("state = %s, want stopped", StateName(got))
+	}
+}
+
+func TestResumeStartsPaused(t *testing.T) {
+	p := NewPlayer()
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	bin := filepath.Join(dir, "mpv")
+	script := `#!/bin/sh
+echo "$@" > ` + args + `
+while true; do
+	echo "A: 00:00:42 / 00:03:00 (23%)"
+	sleep 0.01
+done
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p.bin = bin
+
+	p.Resume(VideoInfo{ID: "file:/a.mp3", Path: "/a.mp3"}, 42.5)
+	waitState(t, p, Paused)
+	data, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--start=42.5", "--pause"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("mpv args %q lack %s", data, want)
+		}
+	}
+	if !strings.HasSuffix(strings.TrimSpace(string(data)), " -- /a.mp3") {
+		t.Errorf("mpv args %q do not end with the stream after --", data)
+	}
+	_ = p.Stop()
+}
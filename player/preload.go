@@ -0,0 +1,128 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+)
+
+// TrackAdvancedMsg is sent when mpv moves on to the preloaded track.
+type TrackAdvancedMsg struct {
+	Video VideoInfo
+}
+
+// Preload resolves video and appends it to the playlist of the running mpv,
+// which opens it ahead and plays it after the current track without a gap.
+// A track already preloaded is kept.
+func (p *Player) Preload(video VideoInfo) error {
+	var (
+		session        int
+		pipe, selector string
+		queued         bool
+	)
+	p.do(func() {
+		if p.proc != nil {
+			session, pipe, queued = p.proc.session, p.proc.pipe, p.proc.next != nil
+		}
+		selector = p.format.Selector()
+	})
+	if pipe == "" {
+		return fmt.Errorf("nothing playing")
+	}
+	if queued {
+		return nil
+	}
+
+	next := &preloaded{video: video, stream: video.Path}
+	if !video.IsLocal() {
+		var err error
+		next.stream, next.chapters, err = getStreamURL(video.ID, selector)
+		if err != nil {
+			return err
+		}
+	}
+	return p.call(func() error {
+		if p.proc == nil || p.proc.session != session {
+			return errReplaced
+		}
+		if p.proc.next != nil {
+			return nil
+		}
+		if _, err := p.requestAt(pipe, "loadfile", next.stream, "append"); err != nil {
+			return err
+		}
+		p.proc.next = next
+		return nil
+	})
+}
+
+// advance follows the mpv of session onto playlist entry pos, the track it
+// preloaded.
+func (p *Player) advance(session, pos int) {
+	if p.proc == nil || p.proc.session != session || pos <= p.proc.playlistPos || p.proc.next == nil {
+		return
+	}
+	next := p.proc.next
+	p.proc.next, p.proc.playlistPos = nil, pos
+	p.proc.videoID = next.video.ID
+	p.stream = next.stream
+	p.chapters = next.chapters
+	p.info = PlayerInfo{Speed: p.speed, VideoID: next.video.ID}
+	p.ending = false
+	logger("player").Debug("gapless advance", "video", next.video.ID)
+	p.bus.Publish(TrackAdvancedMsg{Video: next.video})
+	go p.probeAudio(session, p.proc.pipe)
+}
+
+// watch follows the mpv of session at pipe until it exits: the outputs it
+// sees, and the preloaded track it moves on to.
+func (p *Player) watch(session int, pipe string) {
+	conn, err := net.DialTimeout("unix", pipe, ipcTimeout)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for i, name := range []string{"audio-device-list", "playlist-pos"} {
+		payload, err := json.Marshal(map[string]any{"command": []any{"observe_property", i + 1, name}})
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(append(payload, '\n')); err != nil {
+			return
+		}
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e struct {
+			Event string          `json:"event"`
+			Name  string          `json:"name"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Event != "property-change" {
+			continue
+		}
+		switch e.Name {
+		case "audio-device-list":
+			var devices []AudioDevice
+			if json.Unmarshal(e.Data, &devices) != nil || devices == nil {
+				continue
+			}
+			changed := false
+			p.do(func() {
+				changed = !slices.Equal(p.devices, devices)
+				p.devices = devices
+			})
+			if changed {
+				p.bus.Publish(AudioDevicesChangedMsg(devices))
+			}
+		case "playlist-pos":
+			var pos int
+			if json.Unmarshal(e.Data, &pos) == nil {
+				p.do(func() { p.advance(session, pos) })
+			}
+		}
+	}
+}
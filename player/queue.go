@@ -0,0 +1,180 @@
+package player
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+// QueueChangedMsg is emitted whenever tracks are added, removed or the
+// current position moves.
+type QueueChangedMsg struct {
+	Length  int `json:"length"`
+	Current int `json:"current"`
+}
+
+type Queue struct {
+	mu      sync.Mutex
+	items   []VideoInfo
+	current int
+
+	// shuffle plays the queue in random order, and repeat starts it over
+	// once its last track ended.
+	shuffle, repeat bool
+	// drawn is the track Following chose in shuffle, -1 until one is.
+	drawn int
+}
+
+func NewQueue() *Queue {
+	return &Queue{current: -1, drawn: -1}
+}
+
+func (q *Queue) Add(videos ...VideoInfo) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append(q.items, videos...)
+}
+
+func (q *Queue) Remove(index int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index < 0 || index >= len(q.items) {
+		return fmt.Errorf("queue index %d out of range", index)
+	}
+	q.items = append(q.items[:index], q.items[index+1:]...)
+	q.drawn = -1
+	switch {
+	case index < q.current:
+		q.current--
+	case index == q.current && q.current >= len(q.items):
+		q.current = len(q.items) - 1
+	}
+	return nil
+}
+
+// IndexOf returns the position of the video with the given ID, or -1.
+func (q *Queue) IndexOf(id string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, video := range q.items {
+		if video.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queue) Items() []VideoInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]VideoInfo(nil), q.items...)
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *Queue) Index() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.current
+}
+
+func (q *Queue) Current() (VideoInfo, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.current < 0 || q.current >= len(q.items) {
+		return VideoInfo{}, false
+	}
+	return q.items[q.current], true
+}
+
+// Select moves the current position and returns the video found there.
+func (q *Queue) Select(index int) (VideoInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index < 0 || index >= len(q.items) {
+		return VideoInfo{}, fmt.Errorf("queue index %d out of range", index)
+	}
+	q.current = index
+	q.drawn = -1
+	return q.items[index], nil
+}
+
+func (q *Queue) Next() (VideoInfo, error) {
+	return q.Select(q.Following())
+}
+
+// Following returns the index of the track that plays after the current
+// one, or -1 when the queue ends there. In shuffle it is drawn once per
+// current track, so that every caller agrees on it.
+func (q *Queue) Following() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case len(q.items) == 0:
+		return -1
+	case q.shuffle && len(q.items) > 1:
+		if q.drawn < 0 || q.drawn == q.current || q.drawn >= len(q.items) {
+			q.drawn = rand.IntN(len(q.items) - 1)
+			if q.drawn >= q.current {
+				q.drawn++
+			}
+		}
+		return q.drawn
+	case q.current+1 < len(q.items):
+		return q.current + 1
+	case q.repeat:
+		return 0
+	}
+	return -1
+}
+
+// SetShuffle turns the random order on or off.
+func (q *Queue) SetShuffle(enabled bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shuffle = enabled
+	q.drawn = -1
+}
+
+// SetRepeat turns the repetition of the whole queue on or off.
+func (q *Queue) SetRepeat(enabled bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.repeat = enabled
+}
+
+// Modes reports whether shuffle and repeat are on.
+func (q *Queue) Modes() (shuffle, repeat bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.shuffle, q.repeat
+}
+
+func (q *Queue) Previous() (VideoInfo, error) {
+	return q.Select(q.Index() - 1)
+}
+
+// MarkFailed flags the tracks with the given ID as failed with kind; an
+// empty kind clears the flag. It reports whether anything changed.
+func (q *Queue) MarkFailed(id string, kind ErrorKind) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	changed := false
+	for i := range q.items {
+		if q.items[i].ID == id && q.items[i].Failed != kind {
+			q.items[i].Failed = kind
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (q *Queue) Changed() QueueChangedMsg {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueChangedMsg{Length: len(q.items), Current: q.current}
+}
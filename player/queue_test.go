@@ -0,0 +1,31 @@
+package player
+
+import "testing"
+
+func TestQueueFollowing(t *testing.T) {
+	q := NewQueue()
+	if got := q.Following(); got != -1 {
+		t.Errorf("Following() of an empty queue = %d, want -1", got)
+	}
+	q.Add(VideoInfo{ID: "a"}, VideoInfo{ID: "b"}, VideoInfo{ID: "c"})
+	q.Select(2)
+	if got := q.Following(); got != -1 {
+		t.Errorf("Following() at the end = %d, want -1", got)
+	}
+	q.SetRepeat(true)
+	if got := q.Following(); got != 0 {
+		t.Errorf("Following() at the end with repeat = %d, want 0", got)
+	}
+
+	q.SetShuffle(true)
+	drawn := q.Following()
+	if drawn < 0 || drawn > 1 {
+		t.Fatalf("Following() in shuffle = %d, want another track", drawn)
+	}
+	if got := q.Following(); got != drawn {
+		t.Errorf("Following() drawn again = %d, want %d", got, drawn)
+	}
+	if video, err := q.Next(); err != nil || q.Index() != drawn {
+		t.Errorf("Next() = %+v, %v at %d, want the drawn track %d", video, err, q.Index(), drawn)
+	}
+}
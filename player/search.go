@@ -0,0 +1,175 @@
+package player
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// DefaultSearchTimeout bounds a search when none is configured.
+	DefaultSearchTimeout = 20 * time.Second
+	// searchDebounce is how long a query waits for the next one before
+	// yt-dlp runs.
+	searchDebounce = 250 * time.Millisecond
+)
+
+// SearchCompleteMsg carries the results of the search numbered ID. Err is
+// context.Canceled when it was cancelled and context.DeadlineExceeded when
+// it timed out.
+type SearchCompleteMsg struct {
+	ID      int
+	Query   string
+	Results []VideoInfo
+	Err     error
+}
+
+// SearchYoutube looks query up, giving up after DefaultSearchTimeout.
+func SearchYoutube(query string, maxResult int) ([]VideoInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultSearchTimeout)
+	defer cancel()
+	return SearchYoutubeContext(ctx, query, maxResult)
+}
+
+func SearchYoutubeContext(ctx context.Context, query string, maxResult int) ([]VideoInfo, error) {
+	dl := Ytdlp().FlatPlaylist().DumpJSON()
+
+	searchQuery := fmt.Sprintf("ytsearch%d:%s", maxResult, query)
+	result, err := dl.Run(ctx, searchQuery)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return []VideoInfo{}, fmt.Errorf("search failed: %w", err)
+	}
+
+	var videos []VideoInfo
+	scanner := bufio.NewScanner(strings.NewReader(result.Stdout))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var video VideoInfo
+		if err := json.Unmarshal([]byte(line), &video); err != nil {
+			continue
+		}
+		// yt-dlp lists the results as a playlist of the query.
+		video.Playlist, video.PlaylistIndex = "", 0
+
+		videos = append(videos, video)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading output: %w", err)
+	}
+
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("no videos found")
+	}
+
+	return videos, nil
+}
+
+// Searcher runs one search at a time: starting a search cancels the one in
+// flight, and the results of a replaced search are told apart by their ID.
+type Searcher struct {
+	timeout time.Duration
+	search  func(ctx context.Context, query string, maxResult int) ([]VideoInfo, error)
+
+	mu     sync.Mutex
+	id     int
+	cancel context.CancelFunc
+}
+
+// NewSearcher returns a Searcher giving up on a search after timeout, or
+// DefaultSearchTimeout when it is not positive.
+func NewSearcher(timeout time.Duration) *Searcher {
+	if timeout <= 0 {
+		timeout = DefaultSearchTimeout
+	}
+	return &Searcher{timeout: timeout, search: SearchYoutubeContext}
+}
+
+// Search cancels the search in flight and returns a command running this
+// one, after a short pause in case it is replaced in turn.
+func (s *Searcher) Search(query string, maxResult int) tea.Cmd {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.id++
+	id := s.id
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout+searchDebounce)
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	return func() tea.Msg {
+		defer cancel()
+		msg := SearchCompleteMsg{ID: id, Query: query}
+		select {
+		case <-time.After(searchDebounce):
+			logger("search").Info("searching", "query", query)
+			msg.Results, msg.Err = s.search(ctx, query, maxResult)
+		case <-ctx.Done():
+			msg.Err = ctx.Err()
+		}
+		logSearch(msg)
+		return msg
+	}
+}
+
+// Cancel stops the search in flight and reports whether there was one.
+func (s *Searcher) Cancel() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel == nil {
+		return false
+	}
+	s.cancel()
+	s.cancel = nil
+	return true
+}
+
+// Done reports whether msg answers the latest search, and forgets that
+// search. Replies to replaced searches should be dropped.
+func (s *Searcher) Done(msg SearchCompleteMsg) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg.ID != s.id {
+		return false
+	}
+	s.cancel = nil
+	return true
+}
+
+// Pending reports whether a search is in flight.
+func (s *Searcher) Pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cancel != nil
+}
+
+func logSearch(msg SearchCompleteMsg) {
+	log := logger("search")
+	switch {
+	case errors.Is(msg.Err, context.Canceled):
+		log.Debug("search cancelled", "query", msg.Query)
+	case errors.Is(msg.Err, context.DeadlineExceeded):
+		log.Warn("search timed out", "query", msg.Query)
+	case msg.Err != nil:
+		log.Warn("search failed", "query", msg.Query, "err", msg.Err)
+	default:
+		log.Info("search done", "query", msg.Query, "results", len(msg.Results))
+	}
+}
@@ -0,0 +1,76 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingSearcher answers searches once they are cancelled, or at once
+// for the query "fast".
+func blockingSearcher(timeout time.Duration) *Searcher {
+	s := NewSearcher(timeout)
+	s.search = func(ctx context.Context, query string, _ int) ([]VideoInfo, error) {
+		if query == "fast" {
+			return []VideoInfo{{ID: query}}, nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s
+}
+
+func runSearch(t *testing.T, cmd func() any) SearchCompleteMsg {
+	t.Helper()
+	done := make(chan SearchCompleteMsg, 1)
+	go func() { done <- cmd().(SearchCompleteMsg) }()
+	select {
+	case msg := <-done:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("search did not return")
+		return SearchCompleteMsg{}
+	}
+}
+
+func TestNewSearchCancelsPrevious(t *testing.T) {
+	s := blockingSearcher(time.Minute)
+	first := s.Search("slow", 5)
+	second := s.Search("fast", 5)
+
+	old := runSearch(t, func() any { return first() })
+	if !errors.Is(old.Err, context.Canceled) {
+		t.Errorf("replaced search error = %v, want cancelled", old.Err)
+	}
+	if s.Done(old) {
+		t.Error("reply of a replaced search accepted")
+	}
+	latest := runSearch(t, func() any { return second() })
+	if !s.Done(latest) || latest.Err != nil || len(latest.Results) != 1 {
+		t.Errorf("latest search = %+v, want its results", latest)
+	}
+	if s.Pending() {
+		t.Error("search still pending once done")
+	}
+}
+
+func TestSearchCancelAndTimeout(t *testing.T) {
+	s := blockingSearcher(time.Minute)
+	cmd := s.Search("slow", 5)
+	if !s.Cancel() {
+		t.Fatal("Cancel found no search in flight")
+	}
+	if msg := runSearch(t, func() any { return cmd() }); !errors.Is(msg.Err, context.Canceled) {
+		t.Errorf("cancelled search error = %v", msg.Err)
+	}
+	if s.Cancel() {
+		t.Error("Cancel reported a search after it ended")
+	}
+
+	s = blockingSearcher(10 * time.Millisecond)
+	cmd = s.Search("slow", 5)
+	if msg := runSearch(t, func() any { return cmd() }); !errors.Is(msg.Err, context.DeadlineExceeded) {
+		t.Errorf("slow search error = %v, want a timeout", msg.Err)
+	}
+}
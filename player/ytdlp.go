@@ -0,0 +1,96 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"player/paths"
+
+	"github.com/lrstanley/go-ytdlp"
+)
+
+// YtdlpVersion is the yt-dlp release the managed install is pinned to.
+const YtdlpVersion = ytdlp.Version
+
+// ManagedYtdlp is where the app keeps its own yt-dlp.
+func ManagedYtdlp() string {
+	return filepath.Join(paths.DataDir(), "bin", "yt-dlp")
+}
+
+// Ytdlp returns a yt-dlp command running the managed install if there is
+// one, the one found on PATH otherwise.
+func Ytdlp() *ytdlp.Command {
+	cmd := ytdlp.New()
+	if managed := ManagedYtdlp(); isExecutable(managed) {
+		cmd.SetExecutable(managed)
+	}
+	return cmd
+}
+
+// YtdlpVersionOf runs bin --version.
+func YtdlpVersionOf(ctx context.Context, bin string) (string, error) {
+	out, err := exec.CommandContext(ctx, bin, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// InstallYtdlp downloads YtdlpVersion from the yt-dlp releases, checks it
+// against their signed checksums and installs it as ManagedYtdlp, unless
+// that version is there already. It reports whether it installed.
+func InstallYtdlp(ctx context.Context) (bool, error) {
+	dest := ManagedYtdlp()
+	if version, err := YtdlpVersionOf(ctx, dest); err == nil && version == YtdlpVersion {
+		return false, nil
+	}
+	// go-ytdlp downloads to its own cache, from which the binary is copied.
+	resolved, err := ytdlp.Install(ctx, &ytdlp.InstallOptions{DisableSystem: true})
+	if err != nil {
+		return false, fmt.Errorf("installing yt-dlp %s: %w", YtdlpVersion, err)
+	}
+	if err := copyExecutable(resolved.Executable, dest); err != nil {
+		return false, fmt.Errorf("installing yt-dlp %s: %w", YtdlpVersion, err)
+	}
+	logger("resolver").Info("yt-dlp installed", "version", YtdlpVersion, "path", dest)
+	return true, nil
+}
+
+// copyExecutable replaces dest with a copy of src at once, so a running
+// yt-dlp is never half written.
+func copyExecutable(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".yt-dlp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
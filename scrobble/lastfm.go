@@ -0,0 +1,135 @@
+package scrobble
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const lastFMBaseURL = "https://ws.audioscrobbler.com/2.0/"
+
+// LastFM submits listens with the Last.fm 2.0 API.
+type LastFM struct {
+	APIKey     string
+	Secret     string
+	SessionKey string
+	BaseURL    string
+	Client     *http.Client
+}
+
+func (l *LastFM) Name() string { return "lastfm" }
+
+func (l *LastFM) NowPlaying(ctx context.Context, t Track) error {
+	params := l.trackParams(t)
+	params.Set("method", "track.updateNowPlaying")
+	return l.call(ctx, params, nil)
+}
+
+func (l *LastFM) Scrobble(ctx context.Context, t Track) error {
+	params := l.trackParams(t)
+	params.Set("method", "track.scrobble")
+	params.Set("timestamp", strconv.FormatInt(t.StartedAt.Unix(), 10))
+	return l.call(ctx, params, nil)
+}
+
+// MobileSession exchanges a username and password for a session key.
+func (l *LastFM) MobileSession(ctx context.Context, username, password string) (string, error) {
+	params := url.Values{
+		"method":   {"auth.getMobileSession"},
+		"username": {username},
+		"password": {password},
+	}
+	var resp struct {
+		Session struct {
+			Key string `json:"key"`
+		} `json:"session"`
+	}
+	if err := l.call(ctx, params, &resp); err != nil {
+		return "", err
+	}
+	return resp.Session.Key, nil
+}
+
+func (l *LastFM) trackParams(t Track) url.Values {
+	params := url.Values{
+		"artist": {t.Artist},
+		"track":  {t.Title},
+	}
+	if t.Duration > 0 {
+		params.Set("duration", strconv.Itoa(int(t.Duration)))
+	}
+	return params
+}
+
+// sign adds api_sig: the md5 of every parameter sorted by name and
+// concatenated as namevalue, followed by the shared secret.
+func (l *LastFM) sign(params url.Values) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString(params.Get(k))
+	}
+	b.WriteString(l.Secret)
+	sum := md5.Sum([]byte(b.String()))
+	params.Set("api_sig", hex.EncodeToString(sum[:]))
+}
+
+func (l *LastFM) call(ctx context.Context, params url.Values, result any) error {
+	params.Set("api_key", l.APIKey)
+	if l.SessionKey != "" {
+		params.Set("sk", l.SessionKey)
+	}
+	l.sign(params)
+	params.Set("format", "json")
+
+	base := l.BaseURL
+	if base == "" {
+		base = lastFMBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient(l.Client).Do(req)
+	if err != nil {
+		return &Error{Service: l.Name(), Err: err, Retryable: true}
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	raw := json.NewDecoder(resp.Body)
+	var payload json.RawMessage
+	if err := raw.Decode(&payload); err != nil {
+		return &Error{Service: l.Name(), Err: fmt.Errorf("status %s", resp.Status), Retryable: resp.StatusCode >= 500}
+	}
+	_ = json.Unmarshal(payload, &body)
+	if body.Error != 0 {
+		// 11: service offline, 16: temporarily unavailable, 29: rate limited.
+		retryable := body.Error == 11 || body.Error == 16 || body.Error == 29
+		return &Error{Service: l.Name(), Err: fmt.Errorf("%s (%d)", body.Message, body.Error), Retryable: retryable}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &Error{Service: l.Name(), Err: fmt.Errorf("status %s", resp.Status), Retryable: resp.StatusCode >= 500}
+	}
+	if result != nil {
+		return json.Unmarshal(payload, result)
+	}
+	return nil
+}
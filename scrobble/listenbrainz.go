@@ -0,0 +1,96 @@
+package scrobble
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const listenBrainzBaseURL = "https://api.listenbrainz.org"
+
+// ListenBrainz submits listens with the ListenBrainz API.
+type ListenBrainz struct {
+	Token   string
+	BaseURL string
+	Client  *http.Client
+}
+
+type listenPayload struct {
+	ListenType string   `json:"listen_type"`
+	Payload    []listen `json:"payload"`
+}
+
+type listen struct {
+	ListenedAt    int64         `json:"listened_at,omitempty"`
+	TrackMetadata trackMetadata `json:"track_metadata"`
+}
+
+type trackMetadata struct {
+	ArtistName     string         `json:"artist_name"`
+	TrackName      string         `json:"track_name"`
+	AdditionalInfo additionalInfo `json:"additional_info"`
+}
+
+type additionalInfo struct {
+	DurationMs  int    `json:"duration_ms,omitempty"`
+	OriginURL   string `json:"origin_url,omitempty"`
+	MediaPlayer string `json:"media_player"`
+}
+
+func (l *ListenBrainz) Name() string { return "listenbrainz" }
+
+func (l *ListenBrainz) NowPlaying(ctx context.Context, t Track) error {
+	return l.submit(ctx, "playing_now", t, 0)
+}
+
+func (l *ListenBrainz) Scrobble(ctx context.Context, t Track) error {
+	return l.submit(ctx, "single", t, t.StartedAt.Unix())
+}
+
+func (l *ListenBrainz) submit(ctx context.Context, listenType string, t Track, listenedAt int64) error {
+	body, err := json.Marshal(listenPayload{
+		ListenType: listenType,
+		Payload: []listen{{
+			ListenedAt: listenedAt,
+			TrackMetadata: trackMetadata{
+				ArtistName: t.Artist,
+				TrackName:  t.Title,
+				AdditionalInfo: additionalInfo{
+					DurationMs:  int(t.Duration * 1000),
+					OriginURL:   t.URL,
+					MediaPlayer: "ghost_player",
+				},
+			},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	base := l.BaseURL
+	if base == "" {
+		base = listenBrainzBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(base, "/")+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+l.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient(l.Client).Do(req)
+	if err != nil {
+		return &Error{Service: l.Name(), Err: err, Retryable: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return &Error{Service: l.Name(), Err: fmt.Errorf("status %s: %s", resp.Status, bytes.TrimSpace(msg)), Retryable: retryable}
+	}
+	return nil
+}
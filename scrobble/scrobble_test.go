@@ -0,0 +1,150 @@
+package scrobble
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		title, uploader string
+		artist, track   string
+	}{
+		{"Daft Punk - Around the World (Official Music Video)", "Daft Punk", "Daft Punk", "Around the World"},
+		{"Shenseea – Hit & Run [Official Video]", "ShenseeaVEVO", "Shenseea", "Hit & Run"},
+		{"Blinding Lights (Audio)", "The Weeknd - Topic", "The Weeknd", "Blinding Lights"},
+		{"lofi hip hop radio", "Lofi Girl", "Lofi Girl", "lofi hip hop radio"},
+	}
+	for _, tt := range tests {
+		artist, track := ParseTitle(tt.title, tt.uploader)
+		if artist != tt.artist || track != tt.track {
+			t.Errorf("ParseTitle(%q, %q) = %q, %q, want %q, %q", tt.title, tt.uploader, artist, track, tt.artist, tt.track)
+		}
+	}
+}
+
+func TestLastFMSignedRequests(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form := r.PostForm
+		sig := form.Get("api_sig")
+		check := url.Values{}
+		for k, v := range form {
+			if k != "api_sig" && k != "format" {
+				check[k] = v
+			}
+		}
+		(&LastFM{Secret: "secret"}).sign(check)
+		if check.Get("api_sig") != sig {
+			w.Write([]byte(`{"error": 13, "message": "Invalid method signature supplied"}`))
+			return
+		}
+		mu.Lock()
+		methods = append(methods, form.Get("method"))
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	lfm := &LastFM{APIKey: "key", Secret: "secret", SessionKey: "sk", BaseURL: srv.URL}
+	track := Track{Artist: "A", Title: "T", Duration: 200, StartedAt: time.Unix(1700000000, 0)}
+	if err := lfm.NowPlaying(context.Background(), track); err != nil {
+		t.Fatalf("NowPlaying() error = %v", err)
+	}
+	if err := lfm.Scrobble(context.Background(), track); err != nil {
+		t.Fatalf("Scrobble() error = %v", err)
+	}
+	if len(methods) != 2 || methods[0] != "track.updateNowPlaying" || methods[1] != "track.scrobble" {
+		t.Errorf("methods = %v", methods)
+	}
+}
+
+// TestOfflineQueue plays a track against a ListenBrainz mock that is down,
+// then checks the listen survives a restart and is delivered once it is up.
+func TestOfflineQueue(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		up      bool
+		listens []listenPayload
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p listenPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		listens = append(listens, p)
+	}))
+	defer srv.Close()
+
+	lb := &ListenBrainz{Token: "tok", BaseURL: srv.URL}
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+	s, err := New([]Service{lb}, queuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	s.Started(ctx, Track{Artist: "A", Title: "T", Duration: 100, StartedAt: time.Now()})
+	// Jumping ahead is a seek and must not count as listened.
+	s.Progress(ctx, 30, 100, 1)
+	if s.played != 0 {
+		t.Fatalf("played = %v after a seek, want 0", s.played)
+	}
+	s.lastUpdate = time.Now().Add(-time.Minute)
+	s.Progress(ctx, 90, 100, 1)
+	if s.Pending() != 1 {
+		t.Fatalf("Pending() = %d, want 1 while offline", s.Pending())
+	}
+
+	restarted, err := New([]Service{lb}, queuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.Pending() != 1 {
+		t.Fatalf("Pending() after restart = %d, want 1", restarted.Pending())
+	}
+
+	mu.Lock()
+	up = true
+	mu.Unlock()
+	restarted.Flush(ctx)
+	if restarted.Pending() != 0 {
+		t.Errorf("Pending() after flush = %d, want 0", restarted.Pending())
+	}
+	if len(listens) != 1 || listens[0].ListenType != "single" || listens[0].Payload[0].TrackMetadata.TrackName != "T" {
+		t.Errorf("listens = %+v", listens)
+	}
+}
+
+func TestProgressAtSpeed(t *testing.T) {
+	s, err := New(nil, filepath.Join(t.TempDir(), "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s.Started(ctx, Track{Artist: "A", Title: "T", Duration: 200, StartedAt: time.Now()})
+	s.lastUpdate = time.Now().Add(-10 * time.Second)
+	s.Progress(ctx, 20, 200, 2)
+	if s.played != 20 {
+		t.Errorf("played = %v after 10s at speed 2, want 20", s.played)
+	}
+}
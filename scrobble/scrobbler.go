@@ -0,0 +1,277 @@
+package scrobble
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"player/config"
+	"player/daemon"
+	"player/paths"
+	"player/player"
+)
+
+// Service is a scrobbling backend such as Last.fm or ListenBrainz.
+type Service interface {
+	Name() string
+	NowPlaying(ctx context.Context, t Track) error
+	Scrobble(ctx context.Context, t Track) error
+}
+
+// Error reports a failed submission. Retryable errors are kept in the
+// offline queue, the others are dropped.
+type Error struct {
+	Service   string
+	Err       error
+	Retryable bool
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Service, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+const (
+	// Last.fm only accepts tracks longer than 30 seconds, scrobbled after
+	// half their length or 4 minutes, whichever comes first.
+	minLength    = 30
+	maxThreshold = 240
+
+	requestTimeout = 15 * time.Second
+	flushInterval  = 5 * time.Minute
+)
+
+func httpClient(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return &http.Client{Timeout: requestTimeout}
+}
+
+func DefaultQueuePath() string {
+	return filepath.Join(paths.DataDir(), "scrobble-queue.json")
+}
+
+type pendingScrobble struct {
+	Service string `json:"service"`
+	Track   Track  `json:"track"`
+}
+
+// Scrobbler follows playback and submits listens to every service.
+type Scrobbler struct {
+	services  []Service
+	queuePath string
+
+	mu      sync.Mutex
+	pending []pendingScrobble
+
+	current    *Track
+	played     float64
+	lastPos    float64
+	lastUpdate time.Time
+	submitted  bool
+}
+
+// New creates a scrobbler whose offline queue is persisted at queuePath.
+func New(services []Service, queuePath string) (*Scrobbler, error) {
+	s := &Scrobbler{services: services, queuePath: queuePath}
+	data, err := os.ReadFile(queuePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.pending); err != nil {
+			return nil, fmt.Errorf("reading scrobble queue: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Pending returns how many scrobbles wait for a retry.
+func (s *Scrobbler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// Started announces a new track as now playing.
+func (s *Scrobbler) Started(ctx context.Context, t Track) {
+	s.mu.Lock()
+	s.current = &t
+	s.played = 0
+	s.lastPos = 0
+	s.lastUpdate = time.Now()
+	s.submitted = false
+	s.mu.Unlock()
+
+	for _, svc := range s.services {
+		_ = svc.NowPlaying(ctx, t)
+	}
+}
+
+// Progress feeds the playback position and speed. Only time actually
+// listened counts: a jump forward larger than the track time played at speed
+// since the last update is a seek and is ignored.
+func (s *Scrobbler) Progress(ctx context.Context, position, length, speed float64) {
+	s.mu.Lock()
+	if s.current == nil || s.submitted {
+		s.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	delta := position - s.lastPos
+	if delta > 0 && delta <= now.Sub(s.lastUpdate).Seconds()*cmp.Or(speed, 1)+2 {
+		s.played += delta
+	}
+	s.lastPos = position
+	s.lastUpdate = now
+
+	if s.current.Duration == 0 {
+		s.current.Duration = length
+	}
+	length = s.current.Duration
+	if length < minLength || s.played < min(length/2, maxThreshold) {
+		s.mu.Unlock()
+		return
+	}
+	s.submitted = true
+	t := *s.current
+	s.mu.Unlock()
+
+	s.submit(ctx, t)
+}
+
+func (s *Scrobbler) submit(ctx context.Context, t Track) {
+	for _, svc := range s.services {
+		err := svc.Scrobble(ctx, t)
+		var scrobbleErr *Error
+		if errors.As(err, &scrobbleErr) && scrobbleErr.Retryable {
+			s.mu.Lock()
+			s.pending = append(s.pending, pendingScrobble{Service: svc.Name(), Track: t})
+			s.mu.Unlock()
+		}
+	}
+	s.save()
+}
+
+// Flush retries the queued scrobbles.
+func (s *Scrobbler) Flush(ctx context.Context) {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	byName := make(map[string]Service, len(s.services))
+	for _, svc := range s.services {
+		byName[svc.Name()] = svc
+	}
+	var keep []pendingScrobble
+	for _, p := range pending {
+		svc, ok := byName[p.Service]
+		if !ok {
+			continue
+		}
+		err := svc.Scrobble(ctx, p.Track)
+		var scrobbleErr *Error
+		if errors.As(err, &scrobbleErr) && scrobbleErr.Retryable {
+			keep = append(keep, p)
+		}
+	}
+
+	s.mu.Lock()
+	s.pending = append(keep, s.pending...)
+	s.mu.Unlock()
+	s.save()
+}
+
+func (s *Scrobbler) save() {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.pending, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(s.queuePath, data, 0o600)
+}
+
+// Attach follows the controller's events until the returned closer is
+// closed.
+func (s *Scrobbler) Attach(ctrl daemon.Controller) *Attachment {
+	events, unsubscribe := ctrl.Subscribe()
+	a := &Attachment{unsubscribe: unsubscribe, done: make(chan struct{})}
+	go s.follow(ctrl, events, a.done)
+	return a
+}
+
+type Attachment struct {
+	unsubscribe func()
+	done        chan struct{}
+}
+
+func (a *Attachment) Close() error {
+	a.unsubscribe()
+	<-a.done
+	return nil
+}
+
+func (s *Scrobbler) follow(ctrl daemon.Controller, events <-chan player.PlayerMsg, done chan struct{}) {
+	defer close(done)
+	ctx := context.Background()
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	s.Flush(ctx)
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush(ctx)
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			switch msg := msg.(type) {
+			case player.PlayStartedMsg:
+				status, err := ctrl.Status()
+				if err != nil || status.Track == nil {
+					continue
+				}
+				s.Started(ctx, NewTrack(*status.Track, time.Now()))
+			case player.PlayerProgressMsg:
+				s.Progress(ctx, msg.Position, msg.Length, msg.Speed)
+			}
+		}
+	}
+}
+
+// FromConfig builds the services enabled in the configuration.
+func FromConfig(cfg config.Scrobble) []Service {
+	var services []Service
+	if cfg.LastFM.Enabled && cfg.LastFM.SessionKey != "" {
+		services = append(services, &LastFM{
+			APIKey:     cfg.LastFM.APIKey,
+			Secret:     cfg.LastFM.Secret,
+			SessionKey: cfg.LastFM.SessionKey,
+			BaseURL:    cfg.LastFM.BaseURL,
+		})
+	}
+	if cfg.ListenBrainz.Enabled && cfg.ListenBrainz.Token != "" {
+		services = append(services, &ListenBrainz{
+			Token:   cfg.ListenBrainz.Token,
+			BaseURL: cfg.ListenBrainz.BaseURL,
+		})
+	}
+	return services
+}
@@ -0,0 +1,57 @@
+package scrobble
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"player/player"
+)
+
+// Track is one listen as submitted to the scrobbling services.
+type Track struct {
+	Artist    string    `json:"artist"`
+	Title     string    `json:"title"`
+	Duration  float64   `json:"duration"`
+	URL       string    `json:"url"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+var (
+	// noiseRegex matches the decorations YouTube uploaders add to titles.
+	noiseRegex = regexp.MustCompile(`(?i)\s*[\(\[](official|lyrics?|audio|video|music video|hd|hq|4k|visuali[sz]er|clip officiel|explicit)[^\)\]]*[\)\]]`)
+	spaceRegex = regexp.MustCompile(`\s+`)
+	separators = []string{" - ", " – ", " — ", " | "}
+)
+
+// NewTrack derives artist and title from a video. Titles usually read
+// "Artist - Title (Official Video)"; when there is no separator the
+// uploader is taken as the artist.
+func NewTrack(video player.VideoInfo, startedAt time.Time) Track {
+	artist, title := ParseTitle(video.Title, video.Uploader)
+	return Track{
+		Artist:    artist,
+		Title:     title,
+		Duration:  video.Duration,
+		URL:       video.Location(),
+		StartedAt: startedAt,
+	}
+}
+
+func ParseTitle(videoTitle, uploader string) (artist, title string) {
+	clean := noiseRegex.ReplaceAllString(videoTitle, "")
+	clean = strings.Trim(spaceRegex.ReplaceAllString(clean, " "), " \"'")
+	for _, sep := range separators {
+		if a, t, ok := strings.Cut(clean, sep); ok {
+			return strings.TrimSpace(a), strings.Trim(strings.TrimSpace(t), "\"'")
+		}
+	}
+	return cleanUploader(uploader), clean
+}
+
+func cleanUploader(uploader string) string {
+	uploader = strings.TrimSuffix(uploader, " - Topic")
+	uploader = strings.TrimSuffix(uploader, "VEVO")
+	uploader = strings.TrimSuffix(uploader, " Official")
+	return strings.TrimSpace(uploader)
+}
@@ -0,0 +1,192 @@
+package tui
+
+import (
+	"context"
+	"image"
+	"os"
+	"strings"
+	"time"
+
+	"player/artwork"
+	"player/daemon"
+	"player/player"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	artHeight = 12
+	// artImageID names our image for the kitty graphics protocol.
+	artImageID = 42
+	// artRedrawDelay lets the renderer paint a frame before the sixel image
+	// is drawn over it again.
+	artRedrawDelay = 80 * time.Millisecond
+)
+
+type artLoadedMsg struct {
+	video player.VideoInfo
+	img   image.Image
+	err   error
+}
+
+type artRedrawMsg struct {
+	gen int
+}
+
+// artModel shows the cover of the current track. Half blocks and kitty
+// placeholders are plain text in the frame; sixel images cannot be, so they
+// are drawn over a blank area after the frame has been painted.
+type artModel struct {
+	ctrl     daemon.Controller
+	fetcher  artwork.Fetcher
+	protocol artwork.Protocol
+	trackID  string
+	img      image.Image
+	view     string
+	sixel    []byte
+	gen      int
+	cellW    int
+	cellH    int
+	col      int
+	row      int
+	width    int
+	height   int
+}
+
+func newArt(ctrl daemon.Controller, protocol artwork.Protocol) artModel {
+	cellW, cellH := artwork.CellSize()
+	return artModel{
+		ctrl:     ctrl,
+		fetcher:  artwork.NewFetcher(),
+		protocol: protocol,
+		cellW:    cellW,
+		cellH:    cellH,
+	}
+}
+
+func (m artModel) Enabled() bool {
+	return m.protocol != artwork.None
+}
+
+func (m artModel) Update(msg tea.Msg) (artModel, tea.Cmd) {
+	if !m.Enabled() {
+		return m, nil
+	}
+	switch msg := msg.(type) {
+	case statusMsg:
+		if msg.Track != nil && msg.Track.ID != m.trackID {
+			m.trackID = msg.Track.ID
+			return m, m.loadCmd(*msg.Track)
+		}
+	case player.PlayStartedMsg:
+		if msg.VideoID != m.trackID {
+			m.trackID = msg.VideoID
+			return m, m.statusLoadCmd
+		}
+	case artLoadedMsg:
+		if msg.video.ID != m.trackID {
+			return m, nil
+		}
+		m.img = msg.img
+		return m, m.render()
+	case artRedrawMsg:
+		if msg.gen == m.gen && m.sixel != nil {
+			return m, writeTerminal(m.sixel)
+		}
+	}
+	return m, nil
+}
+
+func (m artModel) statusLoadCmd() tea.Msg {
+	status, err := m.ctrl.Status()
+	if err != nil || status.Track == nil {
+		return artLoadedMsg{err: err}
+	}
+	return m.loadCmd(*status.Track)()
+}
+
+func (m artModel) loadCmd(video player.VideoInfo) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		img, err := m.fetcher.Load(ctx, video)
+		return artLoadedMsg{video: video, img: img, err: err}
+	}
+}
+
+// render prepares the panel content once per image and size, View only
+// returns it.
+func (m *artModel) render() tea.Cmd {
+	m.view, m.sixel = "", nil
+	if m.img == nil {
+		return nil
+	}
+	cols, rows := artwork.Fit(m.img, m.width-2, m.height-2, m.cellW, m.cellH)
+	if cols == 0 {
+		return nil
+	}
+	switch m.protocol {
+	case artwork.Kitty:
+		m.view = artwork.KittyPlaceholder(artImageID, cols, rows)
+		return writeTerminal([]byte(artwork.KittyTransmit(m.img, artImageID, cols, rows, m.cellW, m.cellH)))
+	case artwork.Sixel:
+		m.view = lipgloss.NewStyle().Width(cols).Height(rows).Render("")
+		col := m.col + 1 + (m.width-2-cols)/2
+		row := m.row + 1 + (m.height-2-rows)/2
+		m.sixel, _ = artwork.SixelAt(m.img, col, row, cols, rows, m.cellW, m.cellH)
+		return m.Redraw()
+	default:
+		m.view = artwork.HalfBlocks(m.img, cols, rows)
+	}
+	return nil
+}
+
+// Redraw schedules the sixel image to be painted again once the frame that
+// may have cleared it is on screen. Only the latest request is honoured.
+func (m *artModel) Redraw() tea.Cmd {
+	if m.sixel == nil {
+		return nil
+	}
+	m.gen++
+	gen := m.gen
+	return tea.Tick(artRedrawDelay, func(time.Time) tea.Msg { return artRedrawMsg{gen: gen} })
+}
+
+func (m artModel) View() string {
+	view := m.view
+	if view == "" {
+		view = mutedTextStyle.Render("♪")
+	}
+	return mutedPanelStyle.
+		Width(m.width-2).
+		Height(m.height-2).
+		Align(lipgloss.Center, lipgloss.Center).
+		Render(view)
+}
+
+// SetSize also takes the screen position of the image, which sixel needs.
+func (m *artModel) SetSize(width, height, col, row int) tea.Cmd {
+	if m.width == width && m.height == height && m.col == col && m.row == row {
+		return nil
+	}
+	m.width, m.height, m.col, m.row = width, height, col, row
+	return m.render()
+}
+
+// linesUnder returns the lines of frame the panel covers.
+func (m artModel) linesUnder(frame string) string {
+	lines := strings.Split(frame, "\n")
+	first := min(max(m.row-1, 0), len(lines))
+	last := min(first+m.height, len(lines))
+	return strings.Join(lines[first:last], "\n")
+}
+
+// writeTerminal sends escape sequences straight to the terminal. A single
+// Write on the same file as the renderer never interleaves with a frame.
+func writeTerminal(data []byte) tea.Cmd {
+	return func() tea.Msg {
+		os.Stdout.Write(data)
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"player/daemon"
+	"player/player"
+	"player/styles"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type chaptersLoadedMsg struct {
+	chapters []player.Chapter
+	current  int
+	err      error
+}
+
+type chapterKeyMap struct {
+	up    key.Binding
+	down  key.Binding
+	seek  key.Binding
+	close key.Binding
+}
+
+func newChapterKeyMap() chapterKeyMap {
+	return chapterKeyMap{
+		up:    key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/↓", "chapter")),
+		down:  key.NewBinding(key.WithKeys("down", "j")),
+		seek:  key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "play")),
+		close: key.NewBinding(key.WithKeys("esc", "c"), key.WithHelp("esc", "close")),
+	}
+}
+
+// chapterModel is the modal listing the chapters of the current track to
+// seek to one.
+type chapterModel struct {
+	ctrl     daemon.Controller
+	keys     chapterKeyMap
+	chapters []player.Chapter
+	current  int
+	cursor   int
+	msg      string
+	visible  bool
+	width    int
+	height   int
+}
+
+func newChapters(ctrl daemon.Controller) chapterModel {
+	return chapterModel{ctrl: ctrl, keys: newChapterKeyMap()}
+}
+
+func (m *chapterModel) Open() tea.Cmd {
+	m.visible = true
+	m.chapters = nil
+	m.msg = "Chargement des chapitres..."
+	return m.loadCmd
+}
+
+func (m chapterModel) Update(msg tea.Msg) (chapterModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case chaptersLoadedMsg:
+		m.msg = ""
+		if msg.err != nil {
+			m.msg = fmt.Sprintf("Erreur: %v", msg.err)
+			return m, nil
+		}
+		if len(msg.chapters) == 0 {
+			m.msg = "Aucun chapitre"
+		}
+		m.chapters = msg.chapters
+		m.current = msg.current
+		m.cursor = max(m.current, 0)
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.close):
+			m.visible = false
+		case key.Matches(msg, m.keys.up) && len(m.chapters) > 0:
+			m.cursor = (m.cursor + len(m.chapters) - 1) % len(m.chapters)
+		case key.Matches(msg, m.keys.down) && len(m.chapters) > 0:
+			m.cursor = (m.cursor + 1) % len(m.chapters)
+		case key.Matches(msg, m.keys.seek) && len(m.chapters) > 0:
+			m.visible = false
+			start := m.chapters[m.cursor].Start
+			return m, controlCmd(func() error { return m.ctrl.SetPosition(start) })
+		}
+	}
+	return m, nil
+}
+
+func (m chapterModel) loadCmd() tea.Msg {
+	status, err := m.ctrl.Status()
+	if err != nil {
+		return chaptersLoadedMsg{err: err}
+	}
+	return chaptersLoadedMsg{
+		chapters: status.Chapters,
+		current:  player.ChapterAt(status.Chapters, status.Info.Position),
+	}
+}
+
+func (m chapterModel) View() string {
+	title := listTitleStyle.Render("Chapitres")
+
+	// Only the chapters around the cursor fit on long mixes.
+	rows := max(m.height-6, 1)
+	first := max(min(m.cursor-rows/2, len(m.chapters)-rows), 0)
+	var lines []string
+	for i := first; i < min(first+rows, len(m.chapters)); i++ {
+		c := m.chapters[i]
+		mark := "  "
+		if i == m.current {
+			mark = "● "
+		}
+		row := mark + mutedTextStyle.Render(formatClock(c.Start)) + " " + c.Title
+		if i == m.cursor {
+			row = styles.AccentTextStyle.Bold(true).Render(mark + formatClock(c.Start) + " " + c.Title)
+		}
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(max(m.width-4, 1)).Render(row))
+	}
+
+	var help []string
+	for _, b := range []key.Binding{m.keys.up, m.keys.seek, m.keys.close} {
+		help = append(help, b.Help().Key+" "+b.Help().Desc)
+	}
+	footer := mutedTextStyle.Render(strings.Join(help, " • "))
+	if m.msg != "" {
+		footer = m.msg
+	}
+
+	view := lipgloss.JoinVertical(lipgloss.Left, title, "", strings.Join(lines, "\n"), "", footer)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
+
+func (m *chapterModel) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
@@ -0,0 +1,163 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"player/config"
+	"player/daemon"
+	"player/player"
+	"player/styles"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type devicesLoadedMsg struct {
+	devices []player.AudioDevice
+	current string
+	err     error
+}
+
+type deviceChosenMsg struct {
+	name string
+	err  error
+}
+
+type deviceKeyMap struct {
+	up     key.Binding
+	down   key.Binding
+	choose key.Binding
+	close  key.Binding
+}
+
+func newDeviceKeyMap() deviceKeyMap {
+	return deviceKeyMap{
+		up:     key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/↓", "device")),
+		down:   key.NewBinding(key.WithKeys("down", "j")),
+		choose: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "use")),
+		close:  key.NewBinding(key.WithKeys("esc", "o"), key.WithHelp("esc", "close")),
+	}
+}
+
+// deviceModel is the modal picking the audio output. The choice applies
+// to the playing track and is kept in the config.
+type deviceModel struct {
+	ctrl    daemon.Controller
+	keys    deviceKeyMap
+	devices []player.AudioDevice
+	current string
+	cursor  int
+	msg     string
+	visible bool
+	width   int
+	height  int
+}
+
+func newDevices(ctrl daemon.Controller) deviceModel {
+	return deviceModel{ctrl: ctrl, keys: newDeviceKeyMap()}
+}
+
+func (m *deviceModel) Open() tea.Cmd {
+	m.visible = true
+	m.msg = "Recherche des sorties..."
+	return m.loadCmd
+}
+
+func (m deviceModel) Update(msg tea.Msg) (deviceModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case devicesLoadedMsg:
+		m.msg = ""
+		if msg.err != nil {
+			m.msg = fmt.Sprintf("Erreur: %v", msg.err)
+			return m, nil
+		}
+		m.devices = msg.devices
+		m.current = msg.current
+		m.cursor = max(slices.IndexFunc(m.devices, func(d player.AudioDevice) bool { return d.Name == m.current }), 0)
+	case deviceChosenMsg:
+		if msg.err != nil {
+			m.msg = fmt.Sprintf("Erreur: %v", msg.err)
+			return m, nil
+		}
+		m.current = msg.name
+		m.msg = "Sortie enregistrée"
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.close):
+			m.visible = false
+		case key.Matches(msg, m.keys.up) && len(m.devices) > 0:
+			m.cursor = (m.cursor + len(m.devices) - 1) % len(m.devices)
+		case key.Matches(msg, m.keys.down) && len(m.devices) > 0:
+			m.cursor = (m.cursor + 1) % len(m.devices)
+		case key.Matches(msg, m.keys.choose) && len(m.devices) > 0:
+			return m, m.chooseCmd(m.devices[m.cursor].Name)
+		}
+	}
+	return m, nil
+}
+
+func (m deviceModel) loadCmd() tea.Msg {
+	devices, err := m.ctrl.AudioDevices()
+	if err != nil {
+		return devicesLoadedMsg{err: err}
+	}
+	status, err := m.ctrl.Status()
+	return devicesLoadedMsg{devices: devices, current: status.Device, err: err}
+}
+
+func (m deviceModel) chooseCmd(name string) tea.Cmd {
+	return func() tea.Msg {
+		if err := m.ctrl.SetAudioDevice(name); err != nil {
+			return deviceChosenMsg{err: err}
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return deviceChosenMsg{err: err}
+		}
+		cfg.AudioDevice = name
+		if name == player.AutoDevice {
+			cfg.AudioDevice = ""
+		}
+		return deviceChosenMsg{name: name, err: cfg.Save()}
+	}
+}
+
+func (m deviceModel) View() string {
+	title := listTitleStyle.Render("Sortie audio")
+
+	var rows []string
+	for i, d := range m.devices {
+		mark := "  "
+		if d.Name == m.current {
+			mark = "● "
+		}
+		row := mark + d.Description
+		if d.Description == "" {
+			row = mark + d.Name
+		}
+		if i == m.cursor {
+			row = styles.AccentTextStyle.Bold(true).Render(row)
+		}
+		rows = append(rows, lipgloss.NewStyle().MaxWidth(max(m.width-4, 1)).Render(row))
+	}
+
+	var help []string
+	for _, b := range []key.Binding{m.keys.up, m.keys.choose, m.keys.close} {
+		help = append(help, b.Help().Key+" "+b.Help().Desc)
+	}
+	footer := mutedTextStyle.Render(strings.Join(help, " • "))
+	if m.msg != "" {
+		footer = m.msg
+	}
+
+	view := lipgloss.JoinVertical(lipgloss.Left, title, "", strings.Join(rows, "\n"), "", footer)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
+
+func (m *deviceModel) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
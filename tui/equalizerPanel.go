@@ -0,0 +1,292 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"player/config"
+	"player/daemon"
+	"player/equalizer"
+	"player/styles"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// eqStep is the change of one arrow press in dB; a row of the sliders is
+// two steps high.
+const eqStep = 1.0
+
+type eqLoadedMsg []float64
+
+type eqSavedMsg struct {
+	presets []equalizer.Preset
+	err     error
+}
+
+type eqKeyMap struct {
+	left       key.Binding
+	right      key.Binding
+	up         key.Binding
+	down       key.Binding
+	reset      key.Binding
+	nextPreset key.Binding
+	prevPreset key.Binding
+	save       key.Binding
+	close      key.Binding
+}
+
+func newEqKeyMap() eqKeyMap {
+	return eqKeyMap{
+		left:       key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/→", "band")),
+		right:      key.NewBinding(key.WithKeys("right", "l")),
+		up:         key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/↓", "gain")),
+		down:       key.NewBinding(key.WithKeys("down", "j")),
+		reset:      key.NewBinding(key.WithKeys("0"), key.WithHelp("0", "reset band")),
+		nextPreset: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "preset")),
+		prevPreset: key.NewBinding(key.WithKeys("shift+tab")),
+		save:       key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "save preset")),
+		close:      key.NewBinding(key.WithKeys("esc", "enter", "e"), key.WithHelp("esc", "close")),
+	}
+}
+
+// equalizerModel is the modal that edits the equalizer of the daemon.
+// Every change is sent at once so it can be heard on the playing track;
+// closing the modal keeps the gains for the next start.
+type equalizerModel struct {
+	ctrl    daemon.Controller
+	keys    eqKeyMap
+	presets []equalizer.Preset
+	gains   []float64
+	band    int
+	preset  int
+	naming  bool
+	input   textinput.Model
+	msg     string
+	visible bool
+	width   int
+	height  int
+}
+
+func newEqualizer(ctrl daemon.Controller, cfg config.Equalizer) equalizerModel {
+	input := textinput.New()
+	input.Placeholder = "nom du préréglage"
+	input.CharLimit = 32
+	return equalizerModel{
+		ctrl:    ctrl,
+		keys:    newEqKeyMap(),
+		presets: append(slices.Clone(equalizer.Presets), cfg.Presets...),
+		gains:   equalizer.Normalize(cfg.Gains),
+		preset:  -1,
+		input:   input,
+	}
+}
+
+func (m *equalizerModel) Open() tea.Cmd {
+	m.visible = true
+	m.msg = ""
+	return m.loadCmd
+}
+
+func (m equalizerModel) Update(msg tea.Msg) (equalizerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case eqLoadedMsg:
+		m.gains = equalizer.Normalize(msg)
+		m.preset = m.matchPreset()
+	case eqSavedMsg:
+		if msg.err != nil {
+			m.msg = fmt.Sprintf("Erreur: %v", msg.err)
+		} else if msg.presets != nil {
+			m.presets = append(slices.Clone(equalizer.Presets), msg.presets...)
+			m.preset = m.matchPreset()
+			m.msg = "Préréglage enregistré"
+		}
+	case tea.KeyMsg:
+		if m.naming {
+			return m.updateName(msg)
+		}
+		switch {
+		case key.Matches(msg, m.keys.close):
+			m.visible = false
+			return m, m.saveCmd("")
+		case key.Matches(msg, m.keys.left):
+			m.band = (m.band + len(m.gains) - 1) % len(m.gains)
+		case key.Matches(msg, m.keys.right):
+			m.band = (m.band + 1) % len(m.gains)
+		case key.Matches(msg, m.keys.up):
+			return m, m.set(m.band, m.gains[m.band]+eqStep)
+		case key.Matches(msg, m.keys.down):
+			return m, m.set(m.band, m.gains[m.band]-eqStep)
+		case key.Matches(msg, m.keys.reset):
+			return m, m.set(m.band, 0)
+		case key.Matches(msg, m.keys.nextPreset):
+			return m, m.applyPreset((m.preset + 1) % len(m.presets))
+		case key.Matches(msg, m.keys.prevPreset):
+			return m, m.applyPreset((max(m.preset, 0) + len(m.presets) - 1) % len(m.presets))
+		case key.Matches(msg, m.keys.save):
+			m.naming = true
+			m.input.SetValue("")
+			return m, m.input.Focus()
+		}
+	}
+	return m, nil
+}
+
+func (m equalizerModel) updateName(msg tea.KeyMsg) (equalizerModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.naming = false
+		m.input.Blur()
+		return m, nil
+	case tea.KeyEnter:
+		m.naming = false
+		m.input.Blur()
+		name := strings.TrimSpace(m.input.Value())
+		if name == "" {
+			return m, nil
+		}
+		return m, m.saveCmd(name)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *equalizerModel) set(band int, gain float64) tea.Cmd {
+	m.gains[band] = max(-equalizer.MaxGain, min(gain, equalizer.MaxGain))
+	m.preset = m.matchPreset()
+	return m.applyCmd()
+}
+
+func (m *equalizerModel) applyPreset(i int) tea.Cmd {
+	m.preset = i
+	m.gains = equalizer.Normalize(m.presets[i].Gains)
+	return m.applyCmd()
+}
+
+func (m equalizerModel) applyCmd() tea.Cmd {
+	gains := slices.Clone(m.gains)
+	return controlCmd(func() error { return m.ctrl.SetEqualizer(gains) })
+}
+
+func (m equalizerModel) matchPreset() int {
+	return slices.IndexFunc(m.presets, func(p equalizer.Preset) bool {
+		return slices.Equal(equalizer.Normalize(p.Gains), m.gains)
+	})
+}
+
+func (m equalizerModel) loadCmd() tea.Msg {
+	status, err := m.ctrl.Status()
+	if err != nil {
+		return eqSavedMsg{err: err}
+	}
+	return eqLoadedMsg(status.Equalizer)
+}
+
+// saveCmd stores the current gains in the config and, when a name is
+// given, also as a user preset replacing any of the same name.
+func (m equalizerModel) saveCmd(name string) tea.Cmd {
+	gains := slices.Clone(m.gains)
+	return func() tea.Msg {
+		cfg, err := config.Load()
+		if err != nil {
+			return eqSavedMsg{err: err}
+		}
+		cfg.Equalizer.Gains = gains
+		if equalizer.IsFlat(gains) {
+			cfg.Equalizer.Gains = nil
+		}
+		if name != "" {
+			presets := slices.DeleteFunc(cfg.Equalizer.Presets, func(p equalizer.Preset) bool {
+				return strings.EqualFold(p.Name, name)
+			})
+			cfg.Equalizer.Presets = append(presets, equalizer.Preset{Name: name, Gains: gains})
+		}
+		if err := cfg.Save(); err != nil {
+			return eqSavedMsg{err: err}
+		}
+		if name == "" {
+			return eqSavedMsg{}
+		}
+		return eqSavedMsg{presets: cfg.Equalizer.Presets}
+	}
+}
+
+func (m equalizerModel) View() string {
+	preset := "personnalisé"
+	if m.preset >= 0 {
+		preset = m.presets[m.preset].Name
+	}
+	title := listTitleStyle.Render("Égaliseur") + "  " + mutedTextStyle.Render(preset)
+
+	const colWidth = 5
+	var rows []string
+	for level := equalizer.MaxGain; level >= -equalizer.MaxGain; level -= 2 * eqStep {
+		var b strings.Builder
+		if level == 0 {
+			b.WriteString("   0 ")
+		} else {
+			fmt.Fprintf(&b, "%+4.0f ", level)
+		}
+		for i, g := range m.gains {
+			cell := "  │  "
+			switch {
+			case level == 0:
+				cell = "──┼──"
+			case level > 0 && g >= level, level < 0 && g <= level:
+				cell = " ███ "
+			}
+			if i == m.band {
+				cell = styles.AccentTextStyle.Render(cell)
+			}
+			b.WriteString(cell)
+		}
+		rows = append(rows, b.String())
+	}
+
+	var labels, values strings.Builder
+	labels.WriteString("     ")
+	values.WriteString("     ")
+	for i, f := range equalizer.Frequencies {
+		label := lipgloss.NewStyle().Width(colWidth).Align(lipgloss.Center)
+		value := label
+		if i == m.band {
+			label = label.Inherit(styles.AccentTextStyle).Bold(true)
+			value = value.Inherit(styles.AccentTextStyle)
+		}
+		labels.WriteString(label.Render(equalizer.Label(f)))
+		values.WriteString(value.Render(fmt.Sprintf("%+.0f", m.gains[i])))
+	}
+
+	grid := lipgloss.JoinVertical(lipgloss.Left,
+		strings.Join(rows, "\n"),
+		labels.String(),
+		values.String(),
+	)
+
+	footer := mutedTextStyle.Width(lipgloss.Width(grid)).Render(m.helpLine())
+	if m.naming {
+		footer = m.input.View()
+	} else if m.msg != "" {
+		footer = m.msg
+	}
+
+	view := lipgloss.JoinVertical(lipgloss.Left, title, "", grid, "", footer)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
+
+func (m equalizerModel) helpLine() string {
+	var help []string
+	for _, b := range []key.Binding{m.keys.left, m.keys.up, m.keys.reset, m.keys.nextPreset, m.keys.save, m.keys.close} {
+		help = append(help, b.Help().Key+" "+b.Help().Desc)
+	}
+	return strings.Join(help, " • ")
+}
+
+func (m *equalizerModel) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
@@ -1,74 +1,163 @@
 package tui
 
 import (
+	"fmt"
+
+	"player/daemon"
+	"player/loudness"
 	"player/player"
 	"player/styles"
 
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
+// speedStep is the change of one speed key press.
+const speedStep = 0.25
+
 type endMsg struct{}
 
+// playerEventMsg wraps a message received from the daemon so that the
+// model knows to keep listening after delivering it.
+type playerEventMsg struct {
+	msg player.PlayerMsg
+}
+
+type daemonLostMsg struct{}
+
+type statusMsg daemon.Status
+
 type footer struct {
-	player        *player.Player
+	ctrl          daemon.Controller
+	events        <-chan player.PlayerMsg
 	spinner       spinner.Model
 	progress      progress.Model
 	width         int
 	height        int
 	progressValue float64
+	state         string
+	title         string
+	lost          bool
+	normalization string
+	speed         float64
+	info          player.PlayerInfo
+	vis           visualizerModel
+	videoID       string
+	chapters      []player.Chapter
 }
 
-func newFooter(p *player.Player) footer {
+func newFooter(ctrl daemon.Controller) footer {
+	events, _ := ctrl.Subscribe()
 	return footer{
-		player:   p,
+		ctrl:     ctrl,
+		events:   events,
 		progress: progress.New(progress.WithDefaultGradient()),
+		state:    player.StateName(player.Stopped),
+		speed:    1,
+		vis:      newVisualizer(ctrl),
 	}
 }
 
 func (m footer) Init() tea.Cmd {
 	return tea.Batch(
 		m.listenCmd,
+		m.statusCmd,
 	)
 }
 
-func (m footer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m footer) Update(msg tea.Msg) (footer, tea.Cmd) {
+	var cmd tea.Cmd
+	m.vis, cmd = m.vis.Update(msg)
+
 	switch msg := msg.(type) {
+	case statusMsg:
+		m.state = msg.State
+		m.normalization = msg.Normalization
+		m.speed = msg.Speed
+		m.info = msg.Info
+		m.progressValue = float64(msg.Info.Progress) / 100
+		m.chapters = msg.Chapters
+		if msg.Track != nil {
+			m.title = msg.Track.Title
+			m.videoID = msg.Track.ID
+		}
+	case player.PlayStartedMsg:
+		m.title = msg.Title
+		m.videoID = msg.VideoID
+		m.chapters = nil
+		m.progressValue = 0
+		m.info = player.PlayerInfo{}
+	case player.ChaptersMsg:
+		if msg.VideoID == m.videoID {
+			m.chapters = msg.Chapters
+		}
+	case player.PlayStoppedMsg:
+		m.progressValue = 0
+		m.info = player.PlayerInfo{}
+	case player.SpeedChangedMsg:
+		m.speed = float64(msg)
+	case player.PlayerStateChangedMsg:
+		m.state = string(msg)
 	case player.PlayerProgressMsg:
+		m.info = player.PlayerInfo(msg)
 		m.progressValue = float64(msg.Progress) / 100
 		if msg.Progress == 100 {
-			return m, tea.Batch(
-				m.listenCmd,
-				endCmd,
-			)
+			return m, tea.Batch(cmd, endCmd)
 		}
-		return m, m.listenCmd
-	case player.PlayerMsg:
-		return m, m.listenCmd
+	case daemonLostMsg:
+		m.lost = true
 	}
-	return m, nil
+	return m, cmd
 }
 
 func (m footer) View() string {
-	playButton := styles.ActiveButtonStyle.Padding(0, 1).Margin(0).Render(styles.IconPlay)
+	icon := styles.IconPlay
+	if m.state == player.StateName(player.Playing) || m.state == player.StateName(player.Loading) {
+		icon = styles.IconStop
+	}
+	playButton := styles.ActiveButtonStyle.Padding(0, 1).Margin(0).Render(icon)
 
-	// progressBar := m.progress.View()
-	// content := styles.TrackProgressStyle.Width(m.width).Render(progressBar)
-	// content = lipgloss.JoinHorizontal(lipgloss.Top, playButton, content)
+	title := m.title
+	if m.lost {
+		title = "daemon déconnecté"
+	}
 
 	style := panelStyle.
 		Padding(0, 1).
 		Width(m.width).
 		Height(m.height)
-	return style.
-		Render(
-			playButton,
-			styles.TrackProgressStyle.Width(m.width).Render(
-				m.progress.ViewAs(m.progressValue),
-			),
-		)
-	// return styles.TrackBoxStyle.Width(m.width).Render(content)
+	var badges string
+	if i := player.ChapterAt(m.chapters, m.info.Position); i >= 0 {
+		badges += mutedTextStyle.Render(fmt.Sprintf("  %d/%d ", i+1, len(m.chapters))) +
+			styles.AccentTextStyle.Render(m.chapters[i].Title)
+	}
+	if m.speed != 0 && m.speed != 1 {
+		badges += styles.AccentTextStyle.Render(fmt.Sprintf("  %g×", m.speed))
+	}
+	if format := m.info.Audio.String(); format != "" {
+		badges += mutedTextStyle.Render("  " + format)
+	}
+	if m.normalization != "" && m.normalization != string(loudness.Off) {
+		badges += mutedTextStyle.Render("  norm: " + m.normalization)
+	}
+
+	// The time left is wall clock time, so it follows the speed.
+	bar := m.progress
+	var eta string
+	if m.info.Length > 0 {
+		eta = mutedTextStyle.Render(" -" + formatClock(m.info.Remaining()))
+		bar.Width = max(bar.Width-lipgloss.Width(eta), 0)
+	}
+	rows := []string{
+		lipgloss.JoinHorizontal(lipgloss.Top, playButton, " ", styles.TrackTitleStyle.Render(title), badges),
+		bar.ViewAs(m.progressValue) + eta,
+	}
+	if m.vis.visible {
+		rows = append(rows, m.vis.View())
+	}
+	return style.Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
 }
 
 func (m *footer) SetSize(w, h int) {
@@ -80,12 +169,52 @@ func (m *footer) SetSize(w, h int) {
 	if progressWidth > 0 {
 		m.progress.Width = progressWidth
 	}
+	m.vis.SetSize(w - 2)
 }
 
 func endCmd() tea.Msg {
 	return endMsg{}
 }
 
-func (m *footer) listenCmd() tea.Msg {
-	return <-m.player.Ch()
+func (m footer) listenCmd() tea.Msg {
+	msg, ok := <-m.events
+	if !ok {
+		return daemonLostMsg{}
+	}
+	return playerEventMsg{msg}
+}
+
+// cycleNormalization switches the daemon to the next loudness mode and
+// refreshes the footer with it.
+func (m footer) cycleNormalization() tea.Cmd {
+	next := loudness.Mode(m.normalization).Next()
+	return func() tea.Msg {
+		if err := m.ctrl.SetNormalization(string(next)); err != nil {
+			return player.PlayErrorMsg{Err: err}
+		}
+		return m.statusCmd()
+	}
+}
+
+// stepSpeed changes the speed by delta within the player limits.
+func (m footer) stepSpeed(delta float64) tea.Cmd {
+	speed := max(player.MinSpeed, min(m.speed+delta, player.MaxSpeed))
+	return controlCmd(func() error { return m.ctrl.SetSpeed(speed) })
+}
+
+// formatClock renders seconds as m:ss, or h:mm:ss for long items.
+func formatClock(seconds float64) string {
+	s := int(seconds + 0.5)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+func (m footer) statusCmd() tea.Msg {
+	status, err := m.ctrl.Status()
+	if err != nil {
+		return player.PlayErrorMsg{Err: err}
+	}
+	return statusMsg(status)
 }
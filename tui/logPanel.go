@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"player/player"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logCapacity is how many records the pane keeps.
+const logCapacity = 500
+
+var logLevelStyles = map[slog.Level]lipgloss.Style{
+	slog.LevelDebug: mutedTextStyle,
+	slog.LevelInfo:  lipgloss.NewStyle(),
+	slog.LevelWarn:  lipgloss.NewStyle().Foreground(lipgloss.Color("#E5C07B")),
+	slog.LevelError: lipgloss.NewStyle().Foreground(lipgloss.Color("#C7424A")),
+}
+
+// logLevels are the thresholds the pane cycles through.
+var logLevels = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// logModel shows the recent records of the daemon and of the TUI, at or
+// above a level chosen with the level key.
+type logModel struct {
+	records  []player.LogMsg
+	level    slog.Level
+	visible  bool
+	levelKey key.Binding
+	width    int
+	height   int
+}
+
+func newLogs() logModel {
+	return logModel{
+		level: slog.LevelInfo,
+		levelKey: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "log level"),
+		),
+	}
+}
+
+func (m logModel) Update(msg tea.Msg) logModel {
+	if record, ok := msg.(player.LogMsg); ok {
+		m.records = append(m.records, record)
+		if len(m.records) > logCapacity {
+			m.records = m.records[len(m.records)-logCapacity:]
+		}
+	}
+	return m
+}
+
+// CycleLevel raises the threshold, back to debug after error.
+func (m *logModel) CycleLevel() {
+	for i, level := range logLevels {
+		if level == m.level {
+			m.level = logLevels[(i+1)%len(logLevels)]
+			return
+		}
+	}
+	m.level = slog.LevelInfo
+}
+
+func (m logModel) View() string {
+	title := listTitleStyle.Render("Journal") + " " +
+		mutedTextStyle.Render(fmt.Sprintf("niveau ≥ %s · %s pour changer", m.level, m.levelKey.Help().Key))
+	rows := max(m.height-2, 1)
+
+	var lines []string
+	for i := len(m.records) - 1; i >= 0 && len(lines) < rows; i-- {
+		r := m.records[i]
+		if r.Level < m.level {
+			continue
+		}
+		line := fmt.Sprintf("%s %-5s %-8s %s", r.Time.Format("15:04:05"), r.Level, r.Component, r.Message)
+		line = strings.ReplaceAll(line, "\n", " ")
+		lines = append(lines, logLevelStyles[r.Level].Inline(true).MaxWidth(m.width).Render(line))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, mutedTextStyle.Render("Aucun événement."))
+	}
+	slices.Reverse(lines)
+
+	view := lipgloss.JoinVertical(lipgloss.Left, title, "", strings.Join(lines, "\n"))
+	return lipgloss.NewStyle().Width(m.width).Height(m.height).MaxHeight(m.height).Render(view)
+}
+
+func (m *logModel) SetSize(width, height int) {
+	m.width = max(width, 0)
+	m.height = max(height, 0)
+}
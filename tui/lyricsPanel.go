@@ -0,0 +1,212 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"player/daemon"
+	"player/lyrics"
+	"player/player"
+	"player/styles"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	lyricsTick   = 250 * time.Millisecond
+	lyricsNudge  = 250 * time.Millisecond
+	lyricsMinCol = 30
+)
+
+type lyricsLoadedMsg struct {
+	video  player.VideoInfo
+	lyrics *lyrics.Lyrics
+	err    error
+}
+
+type lyricsTickMsg struct{}
+
+type lyricsKeyMap struct {
+	later   key.Binding
+	earlier key.Binding
+	save    key.Binding
+}
+
+func newLyricsKeyMap() lyricsKeyMap {
+	return lyricsKeyMap{
+		later: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", "lyrics later"),
+		),
+		earlier: key.NewBinding(
+			key.WithKeys("+", "="),
+			key.WithHelp("+", "lyrics earlier"),
+		),
+		save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save lyrics offset"),
+		),
+	}
+}
+
+// lyricsModel shows the lyrics of the current track, following the
+// playback position between progress updates with a local clock.
+type lyricsModel struct {
+	ctrl     daemon.Controller
+	finder   lyrics.Finder
+	keys     lyricsKeyMap
+	video    player.VideoInfo
+	lyrics   *lyrics.Lyrics
+	msg      string
+	visible  bool
+	playing  bool
+	ticking  bool
+	position time.Duration
+	updated  time.Time
+	width    int
+	height   int
+}
+
+func newLyrics(ctrl daemon.Controller, finder lyrics.Finder) lyricsModel {
+	return lyricsModel{
+		ctrl:   ctrl,
+		finder: finder,
+		keys:   newLyricsKeyMap(),
+	}
+}
+
+func (m lyricsModel) Update(msg tea.Msg) (lyricsModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case player.PlayStartedMsg:
+		m.position = 0
+		m.updated = time.Now()
+		if m.visible {
+			return m, m.loadCmd
+		}
+	case player.PlayerProgressMsg:
+		m.position = time.Duration(msg.Position * float64(time.Second))
+		m.updated = time.Now()
+	case statusMsg:
+		m.playing = msg.State == player.StateName(player.Playing)
+		m.position = time.Duration(msg.Info.Position * float64(time.Second))
+		m.updated = time.Now()
+	case player.PlayerStateChangedMsg:
+		m.playing = string(msg) == player.StateName(player.Playing)
+		return m, m.tick()
+	case lyricsLoadedMsg:
+		m.video = msg.video
+		m.lyrics = msg.lyrics
+		m.msg = ""
+		if errors.Is(msg.err, lyrics.ErrNotFound) {
+			m.msg = "No lyrics for this track"
+		} else if msg.err != nil {
+			m.msg = fmt.Sprintf("Error: %v", msg.err)
+		}
+		return m, m.tick()
+	case lyricsTickMsg:
+		m.ticking = false
+		return m, m.tick()
+	case tea.KeyMsg:
+		if !m.visible || m.lyrics == nil {
+			break
+		}
+		switch {
+		case key.Matches(msg, m.keys.earlier):
+			m.lyrics.Offset += lyricsNudge
+		case key.Matches(msg, m.keys.later):
+			m.lyrics.Offset -= lyricsNudge
+		case key.Matches(msg, m.keys.save):
+			if err := m.finder.Save(m.video, m.lyrics); err != nil {
+				m.msg = fmt.Sprintf("Error: %v", err)
+			} else {
+				m.msg = "Lyrics offset saved"
+			}
+		}
+	}
+	return m, nil
+}
+
+// tick keeps the highlighted line moving while the panel is visible and
+// the track is playing.
+func (m *lyricsModel) tick() tea.Cmd {
+	if m.ticking || !m.visible || !m.playing || m.lyrics == nil {
+		return nil
+	}
+	m.ticking = true
+	return tea.Tick(lyricsTick, func(time.Time) tea.Msg { return lyricsTickMsg{} })
+}
+
+func (m *lyricsModel) Toggle() tea.Cmd {
+	m.visible = !m.visible
+	if !m.visible {
+		return nil
+	}
+	return m.loadCmd
+}
+
+func (m lyricsModel) loadCmd() tea.Msg {
+	status, err := m.ctrl.Status()
+	if err != nil {
+		return lyricsLoadedMsg{err: err}
+	}
+	if status.Track == nil {
+		return lyricsLoadedMsg{err: lyrics.ErrNotFound}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	l, err := m.finder.Find(ctx, *status.Track)
+	return lyricsLoadedMsg{video: *status.Track, lyrics: l, err: err}
+}
+
+func (m lyricsModel) currentPosition() time.Duration {
+	if !m.playing {
+		return m.position
+	}
+	return m.position + time.Since(m.updated)
+}
+
+func (m lyricsModel) View() string {
+	style := mutedPanelStyle.
+		Padding(0, 1).
+		Width(m.width - 2).
+		Height(m.height - 2)
+
+	title := "Paroles"
+	if m.lyrics != nil && m.lyrics.Offset != 0 {
+		title += fmt.Sprintf(" (%+.2fs)", m.lyrics.Offset.Seconds())
+	}
+	header := listTitleStyle.Render(title)
+
+	if m.lyrics == nil || len(m.lyrics.Lines) == 0 {
+		return style.Render(header + "\n\n" + mutedTextStyle.Render(m.msg))
+	}
+
+	rows := max(m.height-6, 1)
+	current := m.lyrics.Index(m.currentPosition())
+	start := max(current-rows/2, 0)
+	end := min(start+rows, len(m.lyrics.Lines))
+
+	textWidth := max(m.width-6, 1)
+	lines := make([]string, 0, rows)
+	for i := start; i < end; i++ {
+		text := m.lyrics.Lines[i].Text
+		if i == current {
+			lines = append(lines, styles.AccentTextStyle.Bold(true).Width(textWidth).Render(text))
+		} else {
+			lines = append(lines, mutedTextStyle.Width(textWidth).Render(text))
+		}
+	}
+	body := lipgloss.NewStyle().MaxHeight(rows).Render(strings.Join(lines, "\n"))
+	footer := mutedTextStyle.Render(m.msg)
+	return style.Render(lipgloss.JoinVertical(lipgloss.Left, header, "", body, footer))
+}
+
+func (m *lyricsModel) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"player/styles"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+type remoteURLMsg string
+
+type remoteModel struct {
+	url    string
+	qr     string
+	width  int
+	height int
+}
+
+func (m remoteModel) Update(msg tea.Msg) remoteModel {
+	if url, ok := msg.(remoteURLMsg); ok {
+		m.url = string(url)
+		m.qr = ""
+		if q, err := qrcode.New(m.url, qrcode.Low); err == nil {
+			m.qr = q.ToSmallString(false)
+		}
+	}
+	return m
+}
+
+func (m remoteModel) View() string {
+	var view string
+	if m.url == "" {
+		view = "Télécommande indisponible.\n\n" +
+			"Lancez le daemon avec -http -http-addr 0.0.0.0:8765\n" +
+			"pour piloter le lecteur depuis un téléphone."
+	} else {
+		view = lipgloss.JoinVertical(lipgloss.Center,
+			"Scannez pour ouvrir la télécommande",
+			m.qr,
+			styles.AccentTextStyle.Render(m.url),
+		)
+	}
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
+
+func (m *remoteModel) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m Model) remoteCmd() tea.Msg {
+	status, err := m.ctrl.Status()
+	if err != nil {
+		return remoteURLMsg("")
+	}
+	return remoteURLMsg(status.RemoteURL)
+}
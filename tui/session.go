@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"player/paths"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// uiSession is what the interface puts back on launch. The queue and the
+// playback are restored by the daemon.
+type uiSession struct {
+	Search   string `json:"search,omitempty"`
+	Platform string `json:"platform,omitempty"`
+}
+
+func uiSessionPath() string {
+	return filepath.Join(paths.StateDir(), "tui.json")
+}
+
+// loadUISession reads the saved session; a missing or broken file yields
+// an empty one.
+func loadUISession() uiSession {
+	var s uiSession
+	if data, err := os.ReadFile(uiSessionPath()); err == nil {
+		_ = json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+func (s uiSession) saveCmd() tea.Msg {
+	if err := s.save(); err != nil {
+		slog.With("component", "tui").Warn("saving session", "err", err)
+	}
+	return nil
+}
+
+func (s uiSession) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := uiSessionPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, uiSessionPath())
+}
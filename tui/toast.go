@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"player/daemon"
+	"player/player"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const toastTimeout = 10 * time.Second
+
+var toastStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FFFDF5")).
+	Background(lipgloss.Color("#C7424A")).
+	Padding(0, 1)
+
+type toastExpiredMsg struct {
+	id int
+}
+
+type toastKeyMap struct {
+	retry   key.Binding
+	skip    key.Binding
+	dismiss key.Binding
+}
+
+func newToastKeyMap() toastKeyMap {
+	return toastKeyMap{
+		retry:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "réessayer")),
+		skip:    key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "suivant")),
+		dismiss: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "fermer")),
+	}
+}
+
+// toastModel shows the last playback error above the footer until it
+// expires, is dismissed or a track plays. Failures of a track offer to
+// retry it, when that may help, or to skip to the next one.
+type toastModel struct {
+	ctrl  daemon.Controller
+	keys  toastKeyMap
+	err   error
+	track *player.PlaybackError
+	id    int
+	width int
+}
+
+func newToast(ctrl daemon.Controller) toastModel {
+	return toastModel{ctrl: ctrl, keys: newToastKeyMap()}
+}
+
+func (m toastModel) Visible() bool {
+	return m.err != nil
+}
+
+func (m toastModel) Update(msg tea.Msg) (toastModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case player.PlayErrorMsg:
+		m.err = msg.Err
+		m.track = nil
+		var pe *player.PlaybackError
+		if errors.As(msg.Err, &pe) {
+			m.track = pe
+		}
+		m.id++
+		id := m.id
+		return m, tea.Tick(toastTimeout, func(time.Time) tea.Msg { return toastExpiredMsg{id} })
+	case toastExpiredMsg:
+		if msg.id == m.id {
+			m.err = nil
+		}
+	case player.PlayerStateChangedMsg:
+		if string(msg) == player.StateName(player.Playing) {
+			m.err = nil
+		}
+	}
+	return m, nil
+}
+
+// HandleKey runs the action of a key press and reports whether the toast
+// used it.
+func (m *toastModel) HandleKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, m.keys.dismiss):
+		m.err = nil
+		return nil, true
+	case m.track == nil:
+		return nil, false
+	case key.Matches(msg, m.keys.retry) && m.track.Retryable():
+		m.err = nil
+		return m.retryCmd(m.track.VideoID), true
+	case key.Matches(msg, m.keys.skip):
+		m.err = nil
+		return controlCmd(m.ctrl.Next), true
+	}
+	return nil, false
+}
+
+func (m toastModel) retryCmd(id string) tea.Cmd {
+	return controlCmd(func() error {
+		queue, err := m.ctrl.Queue()
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(queue, func(v player.VideoInfo) bool { return v.ID == id })
+		if i < 0 {
+			return fmt.Errorf("piste absente de la file")
+		}
+		return m.ctrl.PlayIndex(i)
+	})
+}
+
+func (m toastModel) View() string {
+	if m.err == nil {
+		return ""
+	}
+	actions := []key.Binding{m.keys.dismiss}
+	if m.track != nil {
+		actions = []key.Binding{m.keys.skip, m.keys.dismiss}
+		if m.track.Retryable() {
+			actions = slices.Insert(actions, 0, m.keys.retry)
+		}
+	}
+	help := make([]string, len(actions))
+	for i, b := range actions {
+		help[i] = b.Help().Key + " " + b.Help().Desc
+	}
+	// The message is cut short rather than the actions.
+	actionText := "  ·  " + strings.Join(help, " • ")
+	avail := max(m.width-toastStyle.GetHorizontalFrameSize()-lipgloss.Width(actionText), 1)
+	text := lipgloss.NewStyle().Inline(true).MaxWidth(avail).Render("✗ " + m.err.Error())
+	return toastStyle.Width(m.width).Render(text + actionText)
+}
+
+func (m *toastModel) SetSize(width int) {
+	m.width = max(width, 0)
+}
@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"player/daemon"
 	"player/player"
 	"player/styles"
 
@@ -24,11 +27,40 @@ type trackItemModel struct {
 	isSearch     bool
 	isPlaying    bool
 	currentTrack string
-	player       *player.Player
+	ctrl         daemon.Controller
+	searcher     *player.Searcher
+	// lastQuery is the search whose results are listed.
+	lastQuery string
+	// failed holds the kind of failure of the queued tracks that failed.
+	failed map[string]player.ErrorKind
+	// heard holds the share played of the tracks left partway or heard.
+	heard map[string]float64
 }
 
+type queueFailuresMsg map[string]player.ErrorKind
+
+type heardMsg map[string]float64
+
 type trackKeyMap struct {
 	search           key.Binding
+	togglePause      key.Binding
+	next             key.Binding
+	previous         key.Binding
+	startOver        key.Binding
+	nextChapter      key.Binding
+	previousChapter  key.Binding
+	showChapters     key.Binding
+	enqueue          key.Binding
+	showRemote       key.Binding
+	showLyrics       key.Binding
+	showVisualizer   key.Binding
+	showEqualizer    key.Binding
+	showLogs         key.Binding
+	showDevices      key.Binding
+	normalization    key.Binding
+	speedUp          key.Binding
+	speedDown        key.Binding
+	visualizerStyle  key.Binding
 	toggleSpinner    key.Binding
 	toggleTitleBar   key.Binding
 	toggleStatusBar  key.Binding
@@ -42,6 +74,78 @@ func newListeKeyMap() *trackKeyMap {
 			key.WithKeys("S"),
 			key.WithHelp("S", "search in plateforme"),
 		),
+		togglePause: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "play/pause"),
+		),
+		next: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next track"),
+		),
+		previous: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "previous track"),
+		),
+		startOver: key.NewBinding(
+			key.WithKeys("0"),
+			key.WithHelp("0", "start over"),
+		),
+		nextChapter: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "next chapter"),
+		),
+		previousChapter: key.NewBinding(
+			key.WithKeys("<"),
+			key.WithHelp("<", "previous chapter"),
+		),
+		showChapters: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "chapters"),
+		),
+		enqueue: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "add to queue"),
+		),
+		showRemote: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "phone remote"),
+		),
+		showLyrics: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "lyrics"),
+		),
+		showVisualizer: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "visualizer"),
+		),
+		showEqualizer: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "equalizer"),
+		),
+		showDevices: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "audio output"),
+		),
+		showLogs: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "logs"),
+		),
+		normalization: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "normalization"),
+		),
+		speedUp: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "faster"),
+		),
+		speedDown: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "slower"),
+		),
+		visualizerStyle: key.NewBinding(
+			key.WithKeys("V"),
+			key.WithHelp("V", "bars/braille"),
+		),
 		toggleSpinner: key.NewBinding(
 			key.WithKeys("s"),
 			key.WithHelp("s", "toggle spinner"),
@@ -65,7 +169,7 @@ func newListeKeyMap() *trackKeyMap {
 	}
 }
 
-func newTrackList(p *player.Player) trackItemModel {
+func newTrackList(ctrl daemon.Controller, searcher *player.Searcher, lastQuery string) trackItemModel {
 	var (
 		delegateKey = newDelegateKeyMap()
 		trakKey     = newListeKeyMap()
@@ -75,13 +179,31 @@ func newTrackList(p *player.Player) trackItemModel {
 	const numItem = 2
 	traks := make([]list.Item, numItem)
 
-	delegate := newTrackDelegate(delegateKey, p)
+	delegate := newTrackDelegate(delegateKey)
 	tracks := list.New(traks, delegate, 0, 0)
 	tracks.Title = "Songs"
 	tracks.Styles.Title = styles.TitleStyle
 	tracks.AdditionalFullHelpKeys = func() []key.Binding {
 		return []key.Binding{
 			trakKey.search,
+			trakKey.togglePause,
+			trakKey.next,
+			trakKey.previous,
+			trakKey.startOver,
+			trakKey.previousChapter,
+			trakKey.nextChapter,
+			trakKey.showChapters,
+			trakKey.enqueue,
+			trakKey.showRemote,
+			trakKey.showLyrics,
+			trakKey.showVisualizer,
+			trakKey.visualizerStyle,
+			trakKey.showEqualizer,
+			trakKey.showLogs,
+			trakKey.showDevices,
+			trakKey.normalization,
+			trakKey.speedDown,
+			trakKey.speedUp,
 			trakKey.toggleSpinner,
 			trakKey.toggleStatusBar,
 			trakKey.toggleTitleBar,
@@ -95,13 +217,19 @@ func newTrackList(p *player.Player) trackItemModel {
 		input:        ti,
 		keys:         trakKey,
 		delegateKeys: delegateKey,
-		player: p,
+		ctrl:         ctrl,
+		searcher:     searcher,
+		lastQuery:    lastQuery,
 		isSearch:     false,
 	}
 }
 
+// Init lists again the results of the last search, if any.
 func (m trackItemModel) Init() tea.Cmd {
-	return player.SearchYTCmd("shenseea", 5)
+	if m.lastQuery == "" {
+		return m.heardCmd
+	}
+	return tea.Batch(m.searcher.Search(m.lastQuery, 10), m.heardCmd)
 }
 
 func (m trackItemModel) Update(msg tea.Msg) (trackItemModel, tea.Cmd) {
@@ -122,9 +250,9 @@ func (m trackItemModel) Update(msg tea.Msg) (trackItemModel, tea.Cmd) {
 				query := m.input.Value()
 				if query != "" {
 					m.isSearch = false
-					m.msg = "🔍 Recherche en cours..."
+					m.msg = "🔍 Recherche en cours... (Esc pour annuler)"
 					m.input.SetValue("")
-					return m, player.SearchYTCmd(query, 10)
+					return m, m.searcher.Search(query, 10)
 				}
 			case tea.KeyEsc:
 				m.isSearch = false
@@ -134,24 +262,49 @@ func (m trackItemModel) Update(msg tea.Msg) (trackItemModel, tea.Cmd) {
 			m.input, cmd = m.input.Update(msg)
 			return m, cmd
 		}
-		switch msg.Type{
+		switch msg.Type {
+		case tea.KeyEsc:
+			if m.searcher.Cancel() {
+				m.msg = "Recherche annulée"
+				return m, nil
+			}
 		case tea.KeyEnter:
-			item := m.list.SelectedItem()
-				if item != nil {
-					videoItem, ok := item.(player.TrackItem)
-					if ok {
-						m.player.PlayCmd(videoItem.Info)
-						return m, cmd
-					}
-				}
+			if video, ok := m.selectedVideo(); ok {
+				m.msg = fmt.Sprintf("⏳ Chargement: %s", video.Title)
+				return m, controlCmd(func() error { return m.ctrl.Play(video) })
+			}
 		}
 		switch {
 		case key.Matches(msg, m.keys.search):
 			m.isSearch = true
 			m.input.Focus()
 			return m, textinput.Blink
+		case key.Matches(msg, m.keys.togglePause):
+			return m, controlCmd(m.ctrl.TogglePause)
+		case key.Matches(msg, m.keys.next):
+			return m, controlCmd(m.ctrl.Next)
+		case key.Matches(msg, m.keys.previous):
+			return m, controlCmd(m.ctrl.Previous)
+		case key.Matches(msg, m.keys.startOver):
+			return m, controlCmd(m.ctrl.StartOver)
+		case key.Matches(msg, m.keys.nextChapter):
+			return m, controlCmd(m.ctrl.NextChapter)
+		case key.Matches(msg, m.keys.previousChapter):
+			return m, controlCmd(m.ctrl.PreviousChapter)
+		case key.Matches(msg, m.keys.enqueue):
+			if video, ok := m.selectedVideo(); ok {
+				m.msg = fmt.Sprintf("➕ Ajouté à la file: %s", video.Title)
+				return m, controlCmd(func() error { return m.ctrl.Enqueue(video) })
+			}
 		}
 	case player.SearchCompleteMsg:
+		if !m.searcher.Done(msg) || errors.Is(msg.Err, context.Canceled) {
+			return m, nil
+		}
+		if errors.Is(msg.Err, context.DeadlineExceeded) {
+			m.msg = fmt.Sprintf("Recherche trop longue: %s", msg.Query)
+			return m, nil
+		}
 		if msg.Err != nil {
 			m.msg = fmt.Sprintf("Erreur: %v", msg.Err)
 			return m, nil
@@ -159,14 +312,29 @@ func (m trackItemModel) Update(msg tea.Msg) (trackItemModel, tea.Cmd) {
 
 		items := player.VideoToListeItem(msg.Results)
 		m.list.SetItems(items)
+		m.lastQuery = msg.Query
+		m.mark()
 		m.msg = fmt.Sprintf("%d résultats trouvés", len(items))
 		return m, nil
 
+	case player.QueueChangedMsg:
+		return m, m.failuresCmd
+
+	case queueFailuresMsg:
+		m.failed = msg
+		m.mark()
+		return m, nil
+
+	case heardMsg:
+		m.heard = msg
+		m.mark()
+		return m, nil
+
 	case player.PlayStartedMsg:
 		m.isPlaying = true
 		m.currentTrack = msg.Title
 		m.msg = fmt.Sprintf("▶️  Lecture: %s", msg.Title)
-		return m, nil
+		return m, m.heardCmd
 
 	case player.PlayErrorMsg:
 		m.isPlaying = false
@@ -177,7 +345,7 @@ func (m trackItemModel) Update(msg tea.Msg) (trackItemModel, tea.Cmd) {
 		m.isPlaying = false
 		m.currentTrack = ""
 		m.msg = "⏹️  Lecture arrêtée"
-		return m, nil
+		return m, m.heardCmd
 	}
 	newListModel, cmd := m.list.Update(msg)
 	m.list = newListModel
@@ -185,6 +353,63 @@ func (m trackItemModel) Update(msg tea.Msg) (trackItemModel, tea.Cmd) {
 	return m, cmd
 }
 
+func (m trackItemModel) failuresCmd() tea.Msg {
+	queue, err := m.ctrl.Queue()
+	if err != nil {
+		return player.PlayErrorMsg{Err: err}
+	}
+	failed := make(queueFailuresMsg)
+	for _, video := range queue {
+		if video.Failed != "" {
+			failed[video.ID] = video.Failed
+		}
+	}
+	return failed
+}
+
+// heardCmd reads from the library how much of each track was played.
+func (m trackItemModel) heardCmd() tea.Msg {
+	records, err := m.ctrl.Library()
+	if err != nil {
+		return nil
+	}
+	heard := make(heardMsg)
+	for _, r := range records {
+		if share := r.Heard(); share > 0 {
+			heard[r.Video.ID] = share
+		}
+	}
+	return heard
+}
+
+// mark flags the listed tracks that failed in the queue and how much of
+// them was heard.
+func (m *trackItemModel) mark() {
+	for i, item := range m.list.Items() {
+		track, ok := item.(player.TrackItem)
+		id := track.Info.ID
+		if !ok || track.Info.Failed == m.failed[id] && track.Heard == m.heard[id] {
+			continue
+		}
+		track.Info.Failed = m.failed[id]
+		track.Heard = m.heard[id]
+		m.list.SetItem(i, track)
+	}
+}
+
+// capturesKeys reports whether key presses are being typed into an input.
+func (m trackItemModel) capturesKeys() bool {
+	return m.isSearch || m.list.FilterState() == list.Filtering
+}
+
+func (m trackItemModel) selectedVideo() (player.VideoInfo, bool) {
+	item, ok := m.list.SelectedItem().(player.TrackItem)
+	if !ok {
+		return player.VideoInfo{}, false
+	}
+	return item.Info, true
+}
+
 func (m *trackItemModel) SetSize(width, height int) {
 	m.width = width
 	m.height = height
@@ -231,4 +456,3 @@ func (m trackItemModel) View() string {
 
 	return styles.AppStyle.Render(view)
 }
-
@@ -1,14 +1,46 @@
 package tui
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
 	"player/player"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
-func newTrackDelegate(keys *delegateKeyMap, p *player.Player) list.ItemDelegate {
+// heardBarWidth is the width of the bar under a partly heard track.
+const heardBarWidth = 24
+
+var heardStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#C7424A"))
+
+// trackDelegate renders tracks like the default delegate, with a line
+// under each showing how much of it was heard.
+type trackDelegate struct {
+	list.DefaultDelegate
+}
+
+func (d trackDelegate) Height() int {
+	return d.DefaultDelegate.Height() + 1
+}
+
+func (d trackDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	d.DefaultDelegate.Render(w, m, index, item)
+	track, ok := item.(player.TrackItem)
+	if !ok || track.Heard <= 0 {
+		fmt.Fprint(w, "\n")
+		return
+	}
+	width := min(heardBarWidth, max(m.Width()-4, 1))
+	heard := max(int(track.Heard*float64(width)+0.5), 1)
+	fmt.Fprint(w, "\n  "+heardStyle.Render(strings.Repeat("━", heard))+mutedTextStyle.Render(strings.Repeat("─", width-heard)))
+}
+
+func newTrackDelegate(keys *delegateKeyMap) list.ItemDelegate {
 	d := list.NewDefaultDelegate()
 
 	d.UpdateFunc = func(msg tea.Msg, m *list.Model) tea.Cmd {
@@ -22,7 +54,9 @@ func newTrackDelegate(keys *delegateKeyMap, p *player.Player) list.ItemDelegate
 		return [][]key.Binding{help}
 	}
 
-	return d
+	// The heard bar takes the place of the blank line between tracks.
+	d.SetSpacing(0)
+	return trackDelegate{d}
 }
 
 type delegateKeyMap struct {
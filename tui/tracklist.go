@@ -24,20 +24,28 @@ var plateforms = []plateformItem{
 }
 
 type plateformModel struct {
-	list    list.Model
-	width   int
-	height  int
-	focused bool
+	list list.Model
+	// selected is the platform last chosen.
+	selected string
+	width    int
+	height   int
+	focused  bool
 }
 
-func newPlateformeList() plateformModel {
+func newPlateformeList(selected string) plateformModel {
 	l := list.New(plateformsToListItem(plateforms), newSimpleListDelegate(false), 0, 0)
 	l.Title = "Plateforme"
 	l.DisableQuitKeybindings()
 	l.SetShowStatusBar(false)
 	l.SetShowPagination(true)
+	for i, p := range plateforms {
+		if p.name == selected {
+			l.Select(i)
+		}
+	}
 	return plateformModel{
-		list: l,
+		list:     l,
+		selected: selected,
 	}
 }
 
@@ -54,6 +62,7 @@ func (m plateformModel) Update(msg tea.Msg) (plateformModel, tea.Cmd) {
 		switch msg.Type {
 		case tea.KeyEnter:
 			item := m.list.SelectedItem().(plateformItem)
+			m.selected = item.name
 			m.list.FilterInput.SetValue("")
 			return m, func() tea.Msg { return plateformeSeletedMsg(item) }
 		}
@@ -1,9 +1,14 @@
 package tui
 
 import (
+	"player/artwork"
+	"player/config"
+	"player/daemon"
+	"player/lyrics"
 	"player/player"
 	"player/styles"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -15,7 +20,21 @@ type Model struct {
 	width       int
 	height      int
 	renderCount int
-	player      *player.Player
+	ctrl        daemon.Controller
+	remote      remoteModel
+	showRemote  bool
+	lyrics      lyricsModel
+	art         artModel
+	equalizer   equalizerModel
+	toast       toastModel
+	logs        logModel
+	devices     deviceModel
+	chapters    chapterModel
+	// session is the last saved uiSession.
+	session uiSession
+	// artLines are the lines of the frame under a sixel cover when it was
+	// last drawn.
+	artLines string
 }
 
 var (
@@ -41,13 +60,21 @@ var (
 	footerHeight = 2
 )
 
-func NewModel() Model {
-	p := player.NewPlayer()
+func NewModel(ctrl daemon.Controller, cfg config.Config) Model {
+	session := loadUISession()
 	m := Model{
-		player:    p,
-		footer:    newFooter(p),
-		sidbare:   newPlateformeList(),
-		trackList: newTrackList(p),
+		ctrl:      ctrl,
+		footer:    newFooter(ctrl),
+		sidbare:   newPlateformeList(session.Platform),
+		trackList: newTrackList(ctrl, player.NewSearcher(cfg.SearchTimeoutDuration()), session.Search),
+		lyrics:    newLyrics(ctrl, lyrics.NewFinder(cfg.Lyrics.Dir, cfg.Lyrics.SubLangs)),
+		art:       newArt(ctrl, artwork.ParseProtocol(cfg.Art.Protocol)),
+		equalizer: newEqualizer(ctrl, cfg.Equalizer),
+		toast:     newToast(ctrl),
+		logs:      newLogs(),
+		devices:   newDevices(ctrl),
+		chapters:  newChapters(ctrl),
+		session:   session,
 	}
 	m.width = 80
 	m.height = 24
@@ -58,12 +85,17 @@ func NewModel() Model {
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.trackList.Init(),
+		m.footer.Init(),
 	)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.renderCount++
 	var cmds []tea.Cmd
+	if event, ok := msg.(playerEventMsg); ok {
+		cmds = append(cmds, m.footer.listenCmd)
+		msg = event.msg
+	}
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.Type {
@@ -72,11 +104,122 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyLeft, tea.KeyRight:
 			m.togglePanel()
 		}
+		if m.equalizer.visible {
+			var cmd tea.Cmd
+			m.equalizer, cmd = m.equalizer.Update(msg)
+			return m, cmd
+		}
+		if m.devices.visible {
+			var cmd tea.Cmd
+			m.devices, cmd = m.devices.Update(msg)
+			return m, cmd
+		}
+		if m.chapters.visible {
+			var cmd tea.Cmd
+			m.chapters, cmd = m.chapters.Update(msg)
+			return m, cmd
+		}
+		if m.toast.Visible() && !m.trackList.capturesKeys() {
+			if cmd, ok := m.toast.HandleKey(msg); ok {
+				resize := m.updateSizes()
+				return m, tea.Batch(cmd, resize)
+			}
+		}
+		if key.Matches(msg, m.trackList.keys.showEqualizer) && !m.trackList.capturesKeys() {
+			return m, m.equalizer.Open()
+		}
+		if key.Matches(msg, m.trackList.keys.showDevices) && !m.trackList.capturesKeys() {
+			return m, m.devices.Open()
+		}
+		if key.Matches(msg, m.trackList.keys.showChapters) && !m.trackList.capturesKeys() {
+			return m, m.chapters.Open()
+		}
+		if key.Matches(msg, m.trackList.keys.showRemote) && !m.trackList.capturesKeys() {
+			m.showRemote = !m.showRemote
+			if m.showRemote {
+				return m, m.remoteCmd
+			}
+			return m, nil
+		}
+		if !m.trackList.capturesKeys() {
+			switch {
+			case key.Matches(msg, m.trackList.keys.showLyrics):
+				cmd := m.lyrics.Toggle()
+				return m, tea.Batch(cmd, m.updateSizes())
+			case key.Matches(msg, m.trackList.keys.showVisualizer):
+				cmd := m.footer.vis.Toggle()
+				return m, tea.Batch(cmd, m.updateSizes())
+			case key.Matches(msg, m.trackList.keys.showLogs):
+				m.logs.visible = !m.logs.visible
+				return m, nil
+			case m.logs.visible && key.Matches(msg, m.logs.levelKey):
+				m.logs.CycleLevel()
+				return m, nil
+			case key.Matches(msg, m.trackList.keys.normalization):
+				return m, m.footer.cycleNormalization()
+			case key.Matches(msg, m.trackList.keys.speedUp):
+				return m, m.footer.stepSpeed(speedStep)
+			case key.Matches(msg, m.trackList.keys.speedDown):
+				return m, m.footer.stepSpeed(-speedStep)
+			case key.Matches(msg, m.trackList.keys.visualizerStyle):
+				m.footer.vis.CycleStyle()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.lyrics, cmd = m.lyrics.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
+	case devicesLoadedMsg, deviceChosenMsg:
+		var cmd tea.Cmd
+		m.devices, cmd = m.devices.Update(msg)
+		return m, cmd
+
+	case chaptersLoadedMsg:
+		var cmd tea.Cmd
+		m.chapters, cmd = m.chapters.Update(msg)
+		return m, cmd
+
+	case eqLoadedMsg, eqSavedMsg:
+		var cmd tea.Cmd
+		m.equalizer, cmd = m.equalizer.Update(msg)
+		return m, cmd
+
+	case remoteURLMsg:
+		m.remote = m.remote.Update(msg)
+		return m, nil
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.updateSizes()
+		cmds = append(cmds, m.updateSizes())
+
+	case artRedrawMsg:
+		var cmd tea.Cmd
+		m.art, cmd = m.art.Update(msg)
+		return m, cmd
+	}
+	if _, ok := msg.(tea.KeyMsg); !ok {
+		m.logs = m.logs.Update(msg)
+		shown := m.toast.Visible()
+		var cmdToast tea.Cmd
+		m.toast, cmdToast = m.toast.Update(msg)
+		cmds = append(cmds, cmdToast)
+		if m.toast.Visible() != shown {
+			cmds = append(cmds, m.updateSizes())
+		}
+	}
+	var cmdFooter tea.Cmd
+	m.footer, cmdFooter = m.footer.Update(msg)
+	if cmdFooter != nil {
+		cmds = append(cmds, cmdFooter)
+	}
+	if _, ok := msg.(tea.KeyMsg); !ok {
+		var cmdLyrics tea.Cmd
+		m.lyrics, cmdLyrics = m.lyrics.Update(msg)
+		if cmdLyrics != nil {
+			cmds = append(cmds, cmdLyrics)
+		}
 	}
 	var cmdTrackList tea.Cmd
 	m.trackList, cmdTrackList = m.trackList.Update(msg)
@@ -88,36 +231,140 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if cmdSidbare != nil {
 		cmds = append(cmds, cmdSidbare)
 	}
+	var cmdArt tea.Cmd
+	m.art, cmdArt = m.art.Update(msg)
+	if cmdArt != nil {
+		cmds = append(cmds, cmdArt)
+	}
+	// The renderer repaints a changed line whole, over a sixel cover on it.
+	if m.art.sixel != nil {
+		if lines := m.art.linesUnder(m.View()); lines != m.artLines {
+			m.artLines = lines
+			cmds = append(cmds, m.art.Redraw())
+		}
+	}
+
+	if session := (uiSession{Search: m.trackList.lastQuery, Platform: m.sidbare.selected}); session != m.session {
+		m.session = session
+		cmds = append(cmds, session.saveCmd)
+	}
 
 	return m, tea.Batch(cmds...)
 }
 
-func (m *Model) updateSizes() {
+func (m *Model) updateSizes() tea.Cmd {
 	contentWidth := m.width - sidebarWidth - 4
-	contentHeight := m.height - footerHeight - 6
-	bodyHeight := m.height - footerHeight - 4
+	contentHeight := m.height - m.footerRows() - 6
+	bodyHeight := m.height - m.footerRows() - 4
 
 	if bodyHeight < 15 {
 		bodyHeight = 15
 	}
-	m.footer.SetSize(m.width-2, footerHeight)
+	var cmd tea.Cmd
+	if m.showArt() {
+		contentHeight -= artHeight
+		// The cover sits under the sidebar, inside the body border and padding.
+		cmd = m.art.SetSize(sidebarWidth+2, artHeight, 2, contentHeight+3)
+	}
+	if m.lyrics.visible {
+		lyricsWidth := max(contentWidth/3, lyricsMinCol)
+		contentWidth -= lyricsWidth
+		m.lyrics.SetSize(lyricsWidth, bodyHeight)
+	}
+	m.footer.SetSize(m.width-2, m.footerRows()-m.toastRows())
+	m.toast.SetSize(m.width)
 	m.sidbare.SetSize(sidebarWidth, contentHeight)
 	m.trackList.SetSize(contentWidth, bodyHeight)
+	m.remote.SetSize(contentWidth, bodyHeight-2)
+	m.equalizer.SetSize(contentWidth, bodyHeight-2)
+	m.logs.SetSize(contentWidth, bodyHeight-2)
+	m.devices.SetSize(contentWidth, bodyHeight-2)
+	m.chapters.SetSize(contentWidth, bodyHeight-2)
+	return cmd
+}
+
+// showArt hides the cover when the sidebar would become too short.
+func (m Model) showArt() bool {
+	return m.art.Enabled() && m.height-m.footerRows()-6 >= artHeight+8
+}
+
+// VisualizerShown reports whether the visualizer is on, so it can be
+// turned off in the daemon when the TUI exits.
+func (m Model) VisualizerShown() bool {
+	return m.footer.vis.visible
+}
+
+// footerRows grows the footer by the visualizer when it is shown.
+func (m Model) footerRows() int {
+	rows := footerHeight + m.toastRows()
+	if m.footer.vis.visible {
+		rows += visualizerRows
+	}
+	return rows
+}
+
+// toastRows is the line taken by an error toast over the footer.
+func (m Model) toastRows() int {
+	if m.toast.Visible() {
+		return 1
+	}
+	return 0
 }
 
 func (m Model) View() string {
-	bodyHeight := m.height - footerHeight - 4
+	bodyHeight := m.height - m.footerRows() - 4
 
 	trackListView := m.trackList.View()
+	if m.showRemote {
+		trackListView = m.remote.View()
+	}
+	if m.logs.visible {
+		trackListView = m.logs.View()
+	}
+	if m.equalizer.visible {
+		trackListView = m.equalizer.View()
+	}
+	if m.devices.visible {
+		trackListView = m.devices.View()
+	}
+	if m.chapters.visible {
+		trackListView = m.chapters.View()
+	}
+	sidebarView := m.sidbare.View()
+	if m.showArt() {
+		sidebarView = lipgloss.JoinVertical(lipgloss.Left, sidebarView, m.art.View())
+	}
+	if m.lyrics.visible {
+		width := max(m.width-6-lipgloss.Width(sidebarView)-m.lyrics.width, 0)
+		trackListView = lipgloss.NewStyle().
+			Width(width).
+			MaxWidth(width).
+			Render(trackListView)
+		trackListView = lipgloss.JoinHorizontal(lipgloss.Top, trackListView, m.lyrics.View())
+	}
 	body := styles.TrackBoxStyle.
 		Width(m.width - 2).
 		Height(bodyHeight).
-		Render(lipgloss.JoinHorizontal(lipgloss.Left, m.sidbare.View(), trackListView))
+		Render(lipgloss.JoinHorizontal(lipgloss.Left, sidebarView, trackListView))
 
 	footer := m.footer.View()
+	if m.toast.Visible() {
+		footer = lipgloss.JoinVertical(lipgloss.Left, m.toast.View(), footer)
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, body, footer)
 }
 
 func (m *Model) togglePanel() {
 }
+
+// controlCmd runs a daemon call off the update loop and reports a failure
+// as a PlayErrorMsg.
+func controlCmd(fn func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := fn(); err != nil {
+			return player.PlayErrorMsg{Err: err}
+		}
+		return nil
+	}
+}
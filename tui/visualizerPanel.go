@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"time"
+
+	"player/daemon"
+	"player/player"
+	"player/visualizer"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	visualizerRows = 4
+	visualizerFPS  = 25
+)
+
+type visualizerEnabledMsg struct{ err error }
+
+type visualizerTickMsg struct{}
+
+type visualizerLevelsMsg []float64
+
+// visualizerModel draws the spectrum of the current track in the footer,
+// which the daemon analyses while a client shows it. While hidden or paused
+// it asks for nothing.
+type visualizerModel struct {
+	ctrl    daemon.Controller
+	meter   visualizer.Meter
+	style   visualizer.Style
+	visible bool
+	playing bool
+	// ticking is set while a frame is awaited.
+	ticking bool
+	levels  []float64
+	err     error
+	width   int
+}
+
+func newVisualizer(ctrl daemon.Controller) visualizerModel {
+	return visualizerModel{ctrl: ctrl}
+}
+
+func (m visualizerModel) Update(msg tea.Msg) (visualizerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case statusMsg:
+		m.playing = msg.State == player.StateName(player.Playing)
+		return m, m.tick()
+	case player.PlayerStateChangedMsg:
+		m.playing = string(msg) == player.StateName(player.Playing)
+		if !m.playing {
+			m.clear()
+		}
+		return m, m.tick()
+	case player.PlayStartedMsg:
+		m.clear()
+	case visualizerEnabledMsg:
+		m.err = msg.err
+	case visualizerTickMsg:
+		if !m.visible || !m.playing {
+			m.ticking = false
+			break
+		}
+		return m, m.spectrumCmd
+	case visualizerLevelsMsg:
+		m.ticking = false
+		if m.visible && m.playing {
+			m.levels = m.meter.Update(msg, m.style.Bands(m.width))
+		}
+		return m, m.tick()
+	}
+	return m, nil
+}
+
+func (m *visualizerModel) Toggle() tea.Cmd {
+	m.visible = !m.visible
+	m.err = nil
+	m.clear()
+	ctrl, enabled := m.ctrl, m.visible
+	return tea.Batch(
+		func() tea.Msg { return visualizerEnabledMsg{err: ctrl.SetVisualizer(enabled)} },
+		m.tick(),
+	)
+}
+
+func (m *visualizerModel) CycleStyle() {
+	if m.style == visualizer.Bars {
+		m.style = visualizer.Braille
+	} else {
+		m.style = visualizer.Bars
+	}
+	m.clear()
+}
+
+func (m *visualizerModel) clear() {
+	m.meter.Reset()
+	m.levels = nil
+}
+
+// tick caps the frame rate; frames are only asked for while shown and
+// playing.
+func (m *visualizerModel) tick() tea.Cmd {
+	if m.ticking || !m.visible || !m.playing {
+		return nil
+	}
+	m.ticking = true
+	return tea.Tick(time.Second/visualizerFPS, func(time.Time) tea.Msg { return visualizerTickMsg{} })
+}
+
+// spectrumCmd fetches the levels at the playback position. The daemon has
+// none for a moment while it decodes the audio there, so failures only
+// leave the bars falling.
+func (m visualizerModel) spectrumCmd() tea.Msg {
+	levels, _ := m.ctrl.Spectrum()
+	return visualizerLevelsMsg(levels)
+}
+
+func (m visualizerModel) View() string {
+	if m.err != nil {
+		return mutedTextStyle.Height(visualizerRows).Render("Visualiseur indisponible: " + m.err.Error())
+	}
+	levels := m.levels
+	if levels == nil {
+		levels = make([]float64, m.style.Bands(m.width))
+	}
+	return m.style.Render(levels, visualizerRows)
+}
+
+func (m *visualizerModel) SetSize(width int) {
+	m.width = max(width, 0)
+}
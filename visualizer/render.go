@@ -0,0 +1,74 @@
+package visualizer
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Style is how the spectrum is drawn.
+type Style int
+
+const (
+	// Bars gives each band a column of eighth blocks.
+	Bars Style = iota
+	// Braille packs two bands per cell with four dots of height per row.
+	Braille
+)
+
+// Bands is the number of levels a style needs to fill width cells.
+func (s Style) Bands(width int) int {
+	if s == Braille {
+		return width * 2
+	}
+	return width
+}
+
+// gradient colours the rows from the top, matching the progress bar.
+var gradient = []lipgloss.Color{"#EE6FF8", "#C26BF2", "#9663EA", "#5A56E0"}
+
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// Braille dots of the left and right columns, from the bottom up.
+var (
+	brailleLeft  = []rune{0x40, 0x04, 0x02, 0x01}
+	brailleRight = []rune{0x80, 0x20, 0x10, 0x08}
+)
+
+// Render draws levels over rows lines.
+func (s Style) Render(levels []float64, rows int) string {
+	lines := make([]string, rows)
+	for r := range rows {
+		var line strings.Builder
+		// base is the height, in dots or eighths, below this row.
+		if s == Braille {
+			base := (rows - 1 - r) * 4
+			for i := 0; i < len(levels); i += 2 {
+				dots := rune(0x2800)
+				dots |= brailleColumn(levels[i], rows, base, brailleLeft)
+				if i+1 < len(levels) {
+					dots |= brailleColumn(levels[i+1], rows, base, brailleRight)
+				}
+				line.WriteRune(dots)
+			}
+		} else {
+			base := (rows - 1 - r) * 8
+			for _, l := range levels {
+				fill := int(l*float64(rows*8)) - base
+				line.WriteRune(blocks[min(max(fill, 0), 8)])
+			}
+		}
+		color := gradient[r*len(gradient)/rows]
+		lines[r] = lipgloss.NewStyle().Foreground(color).Render(line.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func brailleColumn(level float64, rows, base int, dots []rune) rune {
+	fill := min(max(int(level*float64(rows*4))-base, 0), 4)
+	var bits rune
+	for _, d := range dots[:fill] {
+		bits |= d
+	}
+	return bits
+}
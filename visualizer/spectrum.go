@@ -0,0 +1,126 @@
+package visualizer
+
+import (
+	"math"
+	"math/cmplx"
+	"slices"
+)
+
+// Bands is the number of frequency bands Levels measures.
+const Bands = 32
+
+const (
+	// SampleRate is what the tap decodes at; 11 kHz of spectrum is plenty
+	// for a few dozen bars.
+	SampleRate = 22050
+	fftSize    = 2048
+	minFreq    = 40.0
+	floorDB    = -60.0
+	// decay is how much of its height a bar keeps per frame when the
+	// signal drops, so bars fall instead of flickering.
+	decay = 0.85
+)
+
+// window is the Hann window applied before the transform.
+var window = func() []float64 {
+	w := make([]float64, fftSize)
+	for i := range w {
+		w[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(fftSize-1)))
+	}
+	return w
+}()
+
+// Levels returns the Bands levels, each in [0, 1], of the last fftSize mono
+// samples, spaced logarithmically from minFreq to the Nyquist frequency. It
+// is nil for fewer samples.
+func Levels(samples []float32) []float64 {
+	if len(samples) < fftSize {
+		return nil
+	}
+	samples = samples[len(samples)-fftSize:]
+	buf := make([]complex128, fftSize)
+	for i, s := range samples {
+		buf[i] = complex(float64(s)*window[i], 0)
+	}
+	fft(buf)
+
+	levels := make([]float64, Bands)
+	maxFreq := float64(SampleRate) / 2
+	binWidth := float64(SampleRate) / fftSize
+	ratio := math.Pow(maxFreq/minFreq, 1.0/Bands)
+	lo := minFreq
+	for i := range levels {
+		hi := lo * ratio
+		first := int(lo / binWidth)
+		last := max(int(hi/binWidth), first+1)
+		var peak float64
+		for b := first; b < last && b < fftSize/2; b++ {
+			peak = max(peak, cmplx.Abs(buf[b]))
+		}
+		// A full scale sine peaks at a quarter of the window length.
+		if peak > 0 {
+			db := 20 * math.Log10(peak/(fftSize/4))
+			levels[i] = min(max((db-floorDB)/-floorDB, 0), 1)
+		}
+		lo = hi
+	}
+	return levels
+}
+
+// fft is an in-place radix-2 Cooley-Tukey transform; len(x) must be a
+// power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range size / 2 {
+				even := x[start+k]
+				odd := w * x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// Meter spreads measured levels over the bars of a display and lets them
+// fall slowly.
+type Meter struct {
+	levels []float64
+}
+
+// Update returns n bars for levels, which may be nil for silence.
+func (m *Meter) Update(levels []float64, n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	if len(m.levels) != n {
+		m.levels = make([]float64, n)
+	}
+	for i := range m.levels {
+		var level float64
+		if len(levels) > 0 {
+			level = levels[i*len(levels)/n]
+		}
+		m.levels[i] = max(level, m.levels[i]*decay)
+	}
+	return slices.Clone(m.levels)
+}
+
+// Reset drops the bars, after a seek or a track change.
+func (m *Meter) Reset() {
+	m.levels = nil
+}
@@ -0,0 +1,123 @@
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// chunkSeconds is how much of a track is decoded at a time.
+	chunkSeconds = 20.0
+	// prefetch is how long before the end of a chunk the next one is
+	// decoded, so the bars never wait for it.
+	prefetch = 5.0
+	// retryAfter spaces the decodes of a track that failed.
+	retryAfter = 2 * time.Second
+)
+
+// Loader decodes the audio of the track playing from start to end seconds
+// into mono samples at SampleRate, with Decode on the file or on what mpv
+// has cached of the stream.
+type Loader func(start, end float64) ([]float32, error)
+
+// Tap keeps a chunk of the decoded audio around the playback position and
+// measures the spectrum at that position. Nothing is decoded unless Levels
+// is called.
+type Tap struct {
+	mu       sync.Mutex
+	chunk    chunk
+	loading  bool
+	failedAt time.Time
+}
+
+type chunk struct {
+	track   string
+	start   float64
+	samples []float32
+	// last is set when the chunk came short, at the end of the track or
+	// of what was cached.
+	last bool
+}
+
+func (c chunk) end() float64 {
+	return c.start + float64(len(c.samples))/SampleRate
+}
+
+// Levels returns the Bands levels of track at position seconds. It is nil
+// until load has decoded the audio there; load runs in the background.
+func (t *Tap) Levels(track string, position float64, load Loader) []float64 {
+	t.mu.Lock()
+	c := t.chunk
+	if c.track != track {
+		c = chunk{}
+	}
+	stale := c.track == "" || position < c.start || position > c.end() ||
+		!c.last && position > c.end()-prefetch
+	if stale && !t.loading && time.Since(t.failedAt) > retryAfter {
+		t.loading = true
+		go t.load(track, max(position-1, 0), load)
+	}
+	t.mu.Unlock()
+
+	i := int((position - c.start) * SampleRate)
+	if c.track == "" || i < fftSize || i > len(c.samples) {
+		return nil
+	}
+	return Levels(c.samples[i-fftSize : i])
+}
+
+func (t *Tap) load(track string, start float64, load Loader) {
+	samples, err := load(start, start+chunkSeconds)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loading = false
+	if err != nil || len(samples) == 0 {
+		t.failedAt = time.Now()
+		return
+	}
+	t.chunk = chunk{
+		track:   track,
+		start:   start,
+		samples: samples,
+		last:    float64(len(samples))/SampleRate < chunkSeconds-1,
+	}
+}
+
+// Reset drops the decoded audio.
+func (t *Tap) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.chunk = chunk{}
+	t.failedAt = time.Time{}
+}
+
+// Decode reads seconds of the audio of file from start with ffmpeg, mixed
+// to mono at SampleRate.
+func Decode(ctx context.Context, file string, start, seconds float64) ([]float32, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-nostdin", "-hide_banner", "-loglevel", "error",
+		"-ss", strconv.FormatFloat(start, 'f', 3, 64),
+		"-t", strconv.FormatFloat(seconds, 'f', 3, 64),
+		"-i", file,
+		"-vn", "-ac", "1", "-ar", strconv.Itoa(SampleRate),
+		"-f", "f32le", "-",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	raw, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	samples := make([]float32, len(raw)/4)
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+	return samples, nil
+}
@@ -0,0 +1,95 @@
+package visualizer
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sine returns n samples of a full scale sine at freq.
+func sine(freq float64, n int) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / SampleRate))
+	}
+	return samples
+}
+
+func TestLevels(t *testing.T) {
+	if got := Levels(make([]float32, fftSize-1)); got != nil {
+		t.Fatalf("Levels() of too few samples = %v, want nil", got)
+	}
+	levels := Levels(sine(1000, fftSize))
+	loudest := slices.Index(levels, slices.Max(levels))
+	// The bands are log spaced from minFreq to 11 kHz.
+	ratio := math.Pow(SampleRate/2/minFreq, 1.0/Bands)
+	want := int(math.Log(1000/minFreq) / math.Log(ratio))
+	if loudest != want || levels[loudest] < 0.9 {
+		t.Errorf("loudest band = %d at %.2f, want %d near 1", loudest, levels[loudest], want)
+	}
+	if levels[0] > 0.3 || levels[Bands-1] > 0.3 {
+		t.Errorf("levels away from 1 kHz = %.2f, %.2f, want low", levels[0], levels[Bands-1])
+	}
+	if silence := Levels(make([]float32, fftSize)); slices.Max(silence) != 0 {
+		t.Errorf("Levels() of silence = %v", silence)
+	}
+}
+
+func TestTap(t *testing.T) {
+	var tap Tap
+	loads := make(chan float64, 10)
+	load := func(start, end float64) ([]float32, error) {
+		loads <- start
+		return sine(1000, int((end-start)*SampleRate)), nil
+	}
+	if levels := tap.Levels("a", 30, load); levels != nil {
+		t.Fatalf("Levels() before decoding = %v, want nil", levels)
+	}
+	if start := <-loads; start != 29 {
+		t.Errorf("decoded from %g, want a second before the position", start)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for tap.Levels("a", 30, load) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no levels once decoded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(loads) != 0 {
+		t.Errorf("decoded again within the chunk")
+	}
+	if levels := tap.Levels("b", 30, load); levels != nil {
+		t.Errorf("Levels() of another track = %v, want nil", levels)
+	}
+	<-loads
+}
+
+func TestMeter(t *testing.T) {
+	var m Meter
+	bars := m.Update([]float64{1, 0.5}, 4)
+	if want := []float64{1, 1, 0.5, 0.5}; !slices.Equal(bars, want) {
+		t.Fatalf("Update() = %v, want %v", bars, want)
+	}
+	fallen := m.Update(nil, 4)
+	for i := range bars {
+		if fallen[i] == 0 || fallen[i] >= bars[i] {
+			t.Errorf("bar %d went from %.2f to %.2f", i, bars[i], fallen[i])
+		}
+	}
+}
+
+func TestRenderSize(t *testing.T) {
+	levels := []float64{0, 0.25, 0.5, 0.75, 1, 1}
+	for style, width := range map[Style]int{Bars: 6, Braille: 3} {
+		out := style.Render(levels, 4)
+		if w, h := lipgloss.Width(out), lipgloss.Height(out); w != width || h != 4 {
+			t.Errorf("style %d: %dx%d, want %dx4", style, w, h, width)
+		}
+	}
+	if got := Bars.Render([]float64{1, 0}, 1); got != "█ " {
+		t.Errorf("Bars = %q", got)
+	}
+}